@@ -0,0 +1,174 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// caScanInterval is how often the cluster autoscaler looks at the cluster,
+// like the Cluster Autoscaler --scan-interval default.
+const caScanInterval = 10 * time.Second
+
+// clusterAutoscaler adds nodes when pods are unschedulable and removes
+// underutilized nodes, like the Kubernetes Cluster Autoscaler.
+//
+// See: https://github.com/kubernetes/autoscaler/blob/master/cluster-autoscaler/FAQ.md
+type clusterAutoscaler struct {
+	enabled              bool
+	minNodes             int
+	maxNodes             int
+	provisioningDelay    time.Duration // time for a new node to become ready
+	unneededTime         time.Duration // --scale-down-unneeded-time
+	utilizationThreshold float64       // --scale-down-utilization-threshold
+	lastScan             time.Time
+}
+
+// run scans the cluster every caScanInterval,
+// scaling it up for unschedulable pods, or else scaling it down.
+func (ca *clusterAutoscaler) run(c *cluster, now time.Time) {
+	if !ca.enabled || now.Sub(ca.lastScan) < caScanInterval {
+		return
+	}
+	ca.lastScan = now
+
+	c.reserve()
+
+	if ca.scaleUp(c, now) {
+		return
+	}
+	ca.scaleDown(c, now)
+}
+
+// scaleUp provisions the nodes needed to fit the unschedulable pods,
+// taking into account the room on nodes still provisioning.
+// Pods too big for an empty node are left alone.
+// It returns true if it added nodes.
+func (ca *clusterAutoscaler) scaleUp(c *cluster, now time.Time) bool {
+	var upcoming []node
+	for _, n := range c.nodes {
+		if n.status(now) == nodeStatusProvisioning {
+			upcoming = append(upcoming, n)
+		}
+	}
+	provisioning := len(upcoming)
+
+	empty := node{cpu: c.nodeCPU, memory: c.nodeMemory}
+	for _, p := range c.pods() {
+		if !p.unschedulable || p.zoneBlocked || !empty.fits(p.cpuRequest, p.memoryRequest) {
+			continue
+		}
+		if !placeFirstFit(upcoming, *p) {
+			upcoming = append(upcoming, empty)
+			placeFirstFit(upcoming, *p)
+		}
+	}
+
+	add := min(len(upcoming)-provisioning, ca.maxNodes-len(c.nodes))
+	for range add {
+		c.addNode(now.Add(ca.provisioningDelay))
+	}
+	return add > 0
+}
+
+// scaleDown drains the node that has been unneeded for unneededTime,
+// if any, as long as the cluster stays above minNodes.
+// Only one node is drained at a time.
+func (ca *clusterAutoscaler) scaleDown(c *cluster, now time.Time) {
+	var active int
+	for _, n := range c.nodes {
+		if n.draining {
+			return // wait for the previous node to go away
+		}
+		active++
+	}
+
+	for i, n := range c.nodes {
+		if n.status(now) != nodeStatusReady || !ca.unneeded(c, n, now) {
+			c.nodes[i].unneededSince = time.Time{}
+			continue
+		}
+		if n.unneededSince.IsZero() {
+			c.nodes[i].unneededSince = now
+		}
+	}
+
+	if active <= ca.minNodes {
+		return
+	}
+
+	for i, n := range c.nodes {
+		if !n.unneededSince.IsZero() && now.Sub(n.unneededSince) >= ca.unneededTime {
+			c.drain(&c.nodes[i], now)
+			return
+		}
+	}
+}
+
+// unneeded tells whether the node utilization is below the threshold
+// and all of its pods fit on the other ready nodes.
+func (ca *clusterAutoscaler) unneeded(c *cluster, n node, now time.Time) bool {
+	return n.utilization() < ca.utilizationThreshold && c.podsFitElsewhere(n, now)
+}
+
+// podsFitElsewhere tells whether all pods on the node fit on the other ready nodes.
+func (c *cluster) podsFitElsewhere(n node, now time.Time) bool {
+	var others []node
+	for _, o := range c.nodes {
+		if o.id != n.id && o.status(now) == nodeStatusReady {
+			others = append(others, o)
+		}
+	}
+
+	for _, p := range c.pods() {
+		if p.node == n.id && p.holdsResources() && !placeFirstFit(others, *p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// placeFirstFit reserves the pod requests on the first node with room for them.
+// It returns false when no node fits the pod.
+func placeFirstFit(nodes []node, p pod) bool {
+	for i, n := range nodes {
+		if n.fits(p.cpuRequest, p.memoryRequest) {
+			nodes[i].cpuRequested += p.cpuRequest
+			nodes[i].memoryRequested += p.memoryRequest
+			return true
+		}
+	}
+	return false
+}
+
+// drain stops scheduling pods on the node and evicts its pods through
+// the Eviction API, subject to their disruption budgets.
+// The node is removed once its pods are gone.
+func (c *cluster) drain(n *node, now time.Time) {
+	n.draining = true
+	c.evictPods(n, now)
+}
+
+// removeDrained retries, every evictionRetryInterval, the evictions the
+// disruption budgets refused on the draining nodes, and removes the
+// draining nodes no pod holds resources on.
+func (c *cluster) removeDrained(now time.Time) {
+	if now.Sub(c.drainRetry) >= evictionRetryInterval {
+		c.drainRetry = now
+		for i := range c.nodes {
+			if c.nodes[i].draining {
+				c.evictPods(&c.nodes[i], now)
+			}
+		}
+	}
+
+	busy := map[int]bool{}
+	for _, p := range c.pods() {
+		if p.holdsResources() {
+			busy[p.node] = true
+		}
+	}
+	c.nodes = slices.DeleteFunc(c.nodes, func(n node) bool {
+		return n.draining && !busy[n.id]
+	})
+}
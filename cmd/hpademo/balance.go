@@ -0,0 +1,163 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+type balancingStrategy string
+
+const (
+	balanceRoundRobin       balancingStrategy = "round-robin"
+	balanceLeastConnections balancingStrategy = "least-connections"
+	balanceRandom           balancingStrategy = "random"
+	balanceSticky           balancingStrategy = "sticky"
+)
+
+// requestCost is the CPU cost of one request, in mCores-second.
+// It turns CPU load into a request count for the random strategy,
+// whose imbalance shrinks as the number of requests per pod grows.
+const requestCost = 10
+
+// balancer distributes the offered load among the serving pods.
+type balancer struct {
+	strategy balancingStrategy
+	skew     float64 // sticky sessions skew factor
+	rng      *rand.Rand
+}
+
+func newBalancer(rng *rand.Rand) *balancer {
+	return &balancer{
+		strategy: balanceRoundRobin,
+		rng:      rng,
+	}
+}
+
+// distribute splits the offered load among pods with the given capacities
+// and traffic weights. A pod with weight 1 takes a full share of the load,
+// a pod with weight 0 takes no load, unless every pod has weight 0 (all of
+// them just became ready), in which case they share the load evenly.
+// It returns the load assigned to each pod, which may exceed the pod capacity:
+// a pod can only serve up to its capacity, the excess is unserved.
+func (b *balancer) distribute(offered float64, capacity, weight []float64) []float64 {
+	if len(capacity) == 0 {
+		return nil
+	}
+	if !slices.ContainsFunc(weight, func(w float64) bool { return w > 0 }) {
+		weight = make([]float64, len(weight))
+		for i := range weight {
+			weight[i] = 1
+		}
+	}
+	switch b.strategy {
+	case balanceLeastConnections:
+		return distributeLeastConnections(offered, capacity, weight)
+	case balanceRandom:
+		return b.distributeRandom(offered, weight)
+	case balanceSticky:
+		return distributeSticky(offered, weight, b.skew)
+	}
+	return distributeRoundRobin(offered, weight)
+}
+
+// distributeProportional splits the load proportionally to the weights.
+func distributeProportional(offered float64, weight []float64) []float64 {
+	assigned := make([]float64, len(weight))
+	var total float64
+	for _, w := range weight {
+		total += w
+	}
+	if total <= 0 {
+		return assigned
+	}
+	for i, w := range weight {
+		assigned[i] = offered * w / total
+	}
+	return assigned
+}
+
+// distributeRoundRobin gives every pod the same share of the load
+// (scaled by its weight), no matter how busy it already is.
+func distributeRoundRobin(offered float64, weight []float64) []float64 {
+	return distributeProportional(offered, weight)
+}
+
+// distributeLeastConnections sends new requests to the pods with fewer
+// requests in flight. Saturated pods hold their connections longer,
+// so the load they cannot take spills over to the pods with spare capacity.
+func distributeLeastConnections(offered float64, capacity, weight []float64) []float64 {
+	assigned := make([]float64, len(capacity))
+
+	// fill pods from the smallest capacity per weight up (water filling)
+	var order []int
+	var totalWeight float64
+	for i, w := range weight {
+		if w > 0 {
+			order = append(order, i)
+			totalWeight += w
+		}
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(capacity[a]/weight[a], capacity[b]/weight[b])
+	})
+
+	remaining := offered
+	for _, i := range order {
+		share := remaining * weight[i] / totalWeight
+		assigned[i] = min(share, capacity[i])
+		remaining -= assigned[i]
+		totalWeight -= weight[i]
+	}
+
+	// nobody can take the rest: spread it as excess
+	if remaining > 0 {
+		excess := distributeProportional(remaining, weight)
+		for i := range assigned {
+			assigned[i] += excess[i]
+		}
+	}
+
+	return assigned
+}
+
+// distributeRandom sends each request to a pod picked at random,
+// with probability proportional to the pod weight.
+// The per-pod request count follows a binomial distribution,
+// approximated here by a normal distribution.
+func (b *balancer) distributeRandom(offered float64, weight []float64) []float64 {
+	assigned := make([]float64, len(weight))
+	remaining := offered / requestCost // requests
+
+	var remainingWeight float64
+	for _, w := range weight {
+		remainingWeight += w
+	}
+
+	for i, w := range weight {
+		if remainingWeight <= 0 {
+			break
+		}
+		p := min(w/remainingWeight, 1)
+		mean := remaining * p
+		stddev := math.Sqrt(remaining * p * (1 - p))
+		n := min(max(mean+stddev*b.rng.NormFloat64(), 0), remaining)
+		assigned[i] = n * requestCost
+		remaining -= n
+		remainingWeight -= w
+	}
+
+	return assigned
+}
+
+// distributeSticky models sticky sessions: older pods have accumulated
+// more sessions than newer ones. The pod at position i (oldest first)
+// gets a share proportional to 1/(i+1)^skew, so skew=0 is an even split.
+func distributeSticky(offered float64, weight []float64, skew float64) []float64 {
+	sessions := make([]float64, len(weight))
+	for i, w := range weight {
+		sessions[i] = w / math.Pow(float64(i+1), skew)
+	}
+	return distributeProportional(offered, sessions)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestDistributeConservesLoad(t *testing.T) {
+	capacity := []float64{100, 300, 600, 50}
+	weight := []float64{1, 0.5, 1, 0.1}
+	for _, strategy := range []balancingStrategy{balanceRoundRobin, balanceLeastConnections, balanceRandom, balanceSticky} {
+		b := newBalancer(rand.New(rand.NewPCG(1, 2)))
+		b.strategy = strategy
+		b.skew = 0.8
+		for _, offered := range []float64{0, 500, 5000} {
+			var total float64
+			for _, load := range b.distribute(offered, capacity, weight) {
+				if load < 0 {
+					t.Fatalf("%s: negative load %v", strategy, load)
+				}
+				total += load
+			}
+			if math.Abs(total-offered) > 1e-6 {
+				t.Errorf("%s: %v of %v offered assigned", strategy, total, offered)
+			}
+		}
+	}
+}
+
+func TestLeastConnectionsWaterFilling(t *testing.T) {
+	capacity := []float64{600, 100, 300}
+	weight := []float64{1, 1, 1}
+
+	// the small pods fill up, the rest spills over to the largest one
+	got := distributeLeastConnections(900, capacity, weight)
+	for i, want := range []float64{500, 100, 300} {
+		if math.Abs(got[i]-want) > 1e-9 {
+			t.Errorf("pod %d: %v, want %v (assigned %v)", i, got[i], want, got)
+		}
+	}
+
+	// beyond the total capacity every pod is full, the excess is spread evenly
+	got = distributeLeastConnections(1300, capacity, weight)
+	for i, want := range []float64{700, 200, 400} {
+		if math.Abs(got[i]-want) > 1e-9 {
+			t.Errorf("overloaded pod %d: %v, want %v (assigned %v)", i, got[i], want, got)
+		}
+	}
+}
+
+func TestRoundRobinIgnoresCapacity(t *testing.T) {
+	got := distributeRoundRobin(900, []float64{1, 1, 0})
+	if got[0] != 450 || got[1] != 450 || got[2] != 0 {
+		t.Errorf("assigned %v, want an even split skipping the pod without weight", got)
+	}
+}
+
+func TestStickyFavorsOlderPods(t *testing.T) {
+	even := distributeSticky(300, []float64{1, 1, 1}, 0)
+	if even[0] != 100 || even[2] != 100 {
+		t.Errorf("skew 0: %v, want an even split", even)
+	}
+
+	// shares 1, 1/2, 1/3 of 11/6
+	got := distributeSticky(1100, []float64{1, 1, 1}, 1)
+	for i, want := range []float64{600, 300, 200} {
+		if math.Abs(got[i]-want) > 1e-9 {
+			t.Errorf("skew 1 pod %d: %v, want %v", i, got[i], want)
+		}
+	}
+}
+
+func TestDistributeAllPodsStartingSlow(t *testing.T) {
+	// every pod just became ready: zero weights still take the load
+	b := newBalancer(rand.New(rand.NewPCG(1, 2)))
+	got := b.distribute(300, []float64{500, 500}, []float64{0, 0})
+	if got[0] != 150 || got[1] != 150 {
+		t.Errorf("assigned %v, want an even split", got)
+	}
+
+	// otherwise a pod at the start of its ramp takes nothing
+	got = b.distribute(300, []float64{500, 500}, []float64{0, 0.5})
+	if got[0] != 0 || got[1] != 300 {
+		t.Errorf("assigned %v, want all the load on the warming pod", got)
+	}
+}
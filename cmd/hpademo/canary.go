@@ -0,0 +1,29 @@
+package main
+
+// trafficSplit routes a share of the traffic of the stable workload
+// to the canary workload, like a weighted route of an ingress or
+// service mesh in front of two versions of the same service.
+type trafficSplit struct {
+	enabled bool
+	weight  float64 // fraction of the traffic sent to the canary
+}
+
+// activeWorkloads returns the number of active workloads:
+// the canary is the second workload, hence it is active along with the stable one.
+func (t trafficSplit) activeWorkloads(count int) int {
+	if t.enabled {
+		return max(count, 2)
+	}
+	return count
+}
+
+// route splits the demand of the stable workload between it and the canary.
+// The canary own demand is ignored: both versions serve the same traffic.
+func (t trafficSplit) route(stable, canary *workload) {
+	if !t.enabled {
+		return
+	}
+	total := stable.demand
+	canary.demand = total * t.weight
+	stable.demand = total - canary.demand
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"syscall/js"
+	"time"
+)
+
+// chaos injects disruptions: random pod kills, like a chaos experiment,
+// and spot instance interruptions, which take a fraction of the nodes
+// away after a short notice period.
+//
+// See: https://kubernetes.io/docs/concepts/workloads/pods/disruptions/
+type chaos struct {
+	podKillRate       float64       // pod kills per hour, across all workloads
+	spotRate          float64       // spot interruptions per hour
+	spotFraction      float64       // fraction of the ready nodes interrupted at once
+	spotNotice        time.Duration // between the interruption notice and the termination
+	provisioningDelay time.Duration // for the node group to replace an interrupted node
+	podKills          int           // pods killed by the experiment
+	interruptions     int           // spot interruption notices sent
+	lastEviction      time.Time
+	rng               *rand.Rand
+}
+
+// run kills pods and interrupts spot instances at random at the configured
+// rates, and takes the nodes under interruption notice one step further.
+func (ch *chaos) run(c *cluster, workloads []*deployment, now time.Time) {
+	if ch.podKillRate > 0 && ch.rng.Float64() < ch.podKillRate/3600 {
+		var pods []*pod
+		var owners []*deployment
+		for _, d := range workloads {
+			for i := range d.podList {
+				if isKillable(d.podList[i]) {
+					pods = append(pods, &d.podList[i])
+					owners = append(owners, d)
+				}
+			}
+		}
+		if len(pods) > 0 {
+			i := ch.rng.IntN(len(pods))
+			ch.kill(c, owners[i], pods[i], now)
+			owners[i].removeDeleted()
+		}
+	}
+
+	if ch.spotRate > 0 && ch.rng.Float64() < ch.spotRate/3600 {
+		ch.interruptSpot(c, now)
+	}
+
+	ch.runInterruptions(c, now)
+}
+
+// isKillable tells whether the pod has a container on a node to kill.
+func isKillable(p pod) bool {
+	return p.node != 0 && p.status != podStatusTerminating && p.status != podStatusFailed
+}
+
+// killPods kills n random pods of the deployment at once.
+func (ch *chaos) killPods(c *cluster, d *deployment, n int, now time.Time) {
+	var pods []*pod
+	for i := range d.podList {
+		if isKillable(d.podList[i]) {
+			pods = append(pods, &d.podList[i])
+		}
+	}
+	ch.rng.Shuffle(len(pods), func(i, j int) { pods[i], pods[j] = pods[j], pods[i] })
+	for _, p := range pods[:min(n, len(pods))] {
+		ch.kill(c, d, p, now)
+	}
+	d.removeDeleted()
+}
+
+// kill force deletes the pod with no grace period: its container is
+// killed on the spot, and the pod is gone as soon as the owner removes
+// the deleted pods, see removeDeleted. The controller replaces it right
+// away, and a StatefulSet may recreate its ordinal.
+func (ch *chaos) kill(c *cluster, d *deployment, p *pod, now time.Time) {
+	p.deletedAt = now
+	ch.podKills++
+	c.events.emit(now, "Normal", "Killing", "pod/"+d.podName(*p), "Killed by chaos experiment")
+}
+
+// interruptSpot sends an interruption notice to a fraction of the ready
+// nodes, at least one. The nodes are cordoned and drained during the
+// notice period, like the node termination handler does.
+func (ch *chaos) interruptSpot(c *cluster, now time.Time) {
+	var ready []int
+	for i, n := range c.nodes {
+		if n.status(now) == nodeStatusReady {
+			ready = append(ready, i)
+		}
+	}
+	if len(ready) == 0 {
+		return
+	}
+	ch.interruptions++
+	ch.rng.Shuffle(len(ready), func(i, j int) { ready[i], ready[j] = ready[j], ready[i] })
+	count := max(int(math.Round(ch.spotFraction*float64(len(ready)))), 1)
+	for _, i := range ready[:min(count, len(ready))] {
+		n := &c.nodes[i]
+		n.interruptAt = now.Add(ch.spotNotice)
+		c.events.emit(now, "Warning", "SpotInterruption", "node/"+nodeName(n.id),
+			fmt.Sprintf("Spot instance interruption notice, terminating in %v", ch.spotNotice))
+	}
+}
+
+// runInterruptions evicts the pods of the nodes under interruption notice,
+// subject to their disruption budgets, and terminates the nodes at the
+// deadline, whatever pods are left. The node group launches a replacement
+// for its nodes, while karpenter provisions for the pods left pending.
+func (ch *chaos) runInterruptions(c *cluster, now time.Time) {
+	retry := now.Sub(ch.lastEviction) >= evictionRetryInterval
+	if retry {
+		ch.lastEviction = now
+	}
+
+	var terminated []node
+	for i := range c.nodes {
+		n := &c.nodes[i]
+		switch {
+		case n.interruptAt.IsZero():
+		case now.Before(n.interruptAt):
+			if retry {
+				c.evictPods(n, now)
+			}
+		default:
+			terminated = append(terminated, *n)
+		}
+	}
+
+	for _, n := range terminated {
+		c.nodes = slices.DeleteFunc(c.nodes, func(o node) bool { return o.id == n.id })
+		message := "Spot instance terminated"
+		if n.instanceType == "" {
+			c.addNode(now.Add(ch.provisioningDelay))
+			message = fmt.Sprintf("Spot instance terminated, replaced with %s", nodeName(c.lastNodeID))
+		}
+		c.events.emit(now, "Warning", "NodeTerminated", "node/"+nodeName(n.id), message)
+	}
+}
+
+// updateChaosLegend shows the pods killed, spot interruptions and nodes under notice.
+func updateChaosLegend(legend js.Value, ch *chaos, c *cluster) {
+	var notice int
+	for _, n := range c.nodes {
+		if !n.interruptAt.IsZero() {
+			notice++
+		}
+	}
+	legend.Call("querySelector", ".pod-kills").Set("innerText", fmt.Sprintf("%d", ch.podKills))
+	legend.Call("querySelector", ".spot-interruptions").Set("innerText", fmt.Sprintf("%d", ch.interruptions))
+	legend.Call("querySelector", ".nodes-under-notice").Set("innerText", fmt.Sprintf("%d", notice))
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"syscall/js"
+)
+
+type subchart struct {
+	ctx    js.Value
+	legend js.Value
+	data   []int
+}
+
+type chart struct {
+	pods           subchart
+	podsByStatus   [podStatusCount]subchart
+	unschedulable  subchart // Pending pods no node has room for
+	desired        subchart // replicas asked by the HPA
+	podsLoad       subchart
+	podsLoadMin    subchart
+	podsLoadMax    subchart
+	podsMemory     subchart
+	podsMemoryMin  subchart
+	podsMemoryMax  subchart
+	throttled      subchart // percent of CFS periods throttled
+	usageOfRequest subchart // CPU usage in percent of the CPU request
+	restarts       subchart
+	unmetLoad      subchart
+	errorRate      subchart
+	nodes          subchart
+	nodesByStatus  [nodeStatusCount]subchart
+	clusterCPU     subchart // percent of allocatable CPU requested
+	clusterMemory  subchart // percent of allocatable memory requested
+	evictions      subchart
+	workloads      subchart // pods of all workloads
+	podsByWorkload [maxWorkloads]subchart
+	zones          subchart // ready pods of the workload
+	podsByZone     [maxZones]subchart
+	canvasWidth    int
+	canvasHeight   int
+}
+
+// chartSample holds the values recorded into the charts every second.
+type chartSample struct {
+	replicas       int
+	byStatus       [podStatusCount]int
+	unschedulable  int // Pending pods no node has room for, included in byStatus
+	desired        int // replicas asked by the HPA, pods may fall short (e.g. quota)
+	podLoad        int // average among running pods
+	podLoadMin     int
+	podLoadMax     int
+	podMemory      int // average among pods with a running container
+	podMemoryMin   int
+	podMemoryMax   int
+	throttled      int // percent of CFS periods throttled, average among ready pods
+	usageOfRequest int // CPU usage of ready pods in percent of their CPU request
+	restarts       int // container restarts, cumulative
+	unmetLoad      int
+	errorRate      int
+	nodesByStatus  [nodeStatusCount]int
+	clusterCPU     int // percent of ready nodes allocatable CPU requested by pods
+	clusterMemory  int // percent of ready nodes allocatable memory requested by pods
+	evictions      int // pods evicted from drained nodes, cumulative
+	podsByWorkload [maxWorkloads]int
+	podsByZone     [maxZones]int // ready pods of the workload in every zone
+}
+
+func updateChart(c *chart, sample chartSample) {
+	c.pods.push(sample.replicas)
+	for status := range podStatusCount {
+		count := sample.byStatus[status]
+		if status == podStatusPending {
+			count -= sample.unschedulable // drawn apart from the other pending pods
+		}
+		c.podsByStatus[status].push(count)
+	}
+	c.unschedulable.push(sample.unschedulable)
+	c.desired.push(sample.desired)
+	var nodes int
+	for status := range nodeStatusCount {
+		c.nodesByStatus[status].push(sample.nodesByStatus[status])
+		nodes += sample.nodesByStatus[status]
+	}
+	c.nodes.push(nodes)
+	c.clusterCPU.push(sample.clusterCPU)
+	c.clusterMemory.push(sample.clusterMemory)
+	c.evictions.push(sample.evictions)
+	var pods int
+	for i, count := range sample.podsByWorkload {
+		c.podsByWorkload[i].push(count)
+		pods += count
+	}
+	c.workloads.push(pods)
+	var ready int
+	for z, count := range sample.podsByZone {
+		c.podsByZone[z].push(count)
+		ready += count
+	}
+	c.zones.push(ready)
+	c.podsLoad.push(sample.podLoad)
+	c.podsLoadMin.push(sample.podLoadMin)
+	c.podsLoadMax.push(sample.podLoadMax)
+	c.podsMemory.push(sample.podMemory)
+	c.podsMemoryMin.push(sample.podMemoryMin)
+	c.podsMemoryMax.push(sample.podMemoryMax)
+	c.throttled.push(sample.throttled)
+	c.usageOfRequest.push(sample.usageOfRequest)
+	c.restarts.push(sample.restarts)
+	c.unmetLoad.push(sample.unmetLoad)
+	c.errorRate.push(sample.errorRate)
+}
+
+// push shifts data left and adds the new value at the end.
+func (s *subchart) push(value int) {
+	last := len(s.data) - 1
+	copy(s.data, s.data[1:])
+	s.data[last] = value
+}
+
+func (c *chart) subcharts() []*subchart {
+	list := []*subchart{
+		&c.pods,
+		&c.podsLoad,
+		&c.podsLoadMin,
+		&c.podsLoadMax,
+		&c.podsMemory,
+		&c.podsMemoryMin,
+		&c.podsMemoryMax,
+		&c.restarts,
+		&c.unmetLoad,
+		&c.errorRate,
+		&c.unschedulable,
+		&c.desired,
+		&c.nodes,
+		&c.clusterCPU,
+		&c.clusterMemory,
+		&c.evictions,
+		&c.throttled,
+		&c.usageOfRequest,
+		&c.workloads,
+		&c.zones,
+	}
+	for status := range podStatusCount {
+		list = append(list, &c.podsByStatus[status])
+	}
+	for status := range nodeStatusCount {
+		list = append(list, &c.nodesByStatus[status])
+	}
+	for i := range maxWorkloads {
+		list = append(list, &c.podsByWorkload[i])
+	}
+	for z := range maxZones {
+		list = append(list, &c.podsByZone[z])
+	}
+	return list
+}
+
+func (c *chart) resizeHistory(newSize int) {
+	if newSize == len(c.pods.data) {
+		// no change
+		return
+	}
+
+	for _, sc := range c.subcharts() {
+		sc.data = resizeSliceInt(sc.data, newSize)
+	}
+}
+
+func resizeSliceInt(oldSlice []int, newSize int) []int {
+	if newSize == len(oldSlice) {
+		// no change
+		return oldSlice
+	}
+
+	newSlice := make([]int, newSize)
+
+	// copy existing data to new slice
+	copySize := min(len(oldSlice), newSize)
+
+	copy(newSlice[newSize-copySize:], oldSlice[len(oldSlice)-copySize:])
+
+	return newSlice
+}
+
+// newSubchart creates a subchart drawn into the canvas with the given id.
+// The legend element, if any, has the canvas id followed by "_legend".
+func newSubchart(document js.Value, canvasID string, historySize int) subchart {
+	canvas := document.Call("getElementById", canvasID)
+	return subchart{
+		ctx:    canvas.Call("getContext", "2d"),
+		legend: document.Call("getElementById", canvasID+"_legend"),
+		data:   make([]int, historySize),
+	}
+}
+
+// newHiddenSubchart creates a subchart drawn along with the main subchart,
+// sharing its canvas and without a legend of its own.
+func newHiddenSubchart(main subchart, historySize int) subchart {
+	return subchart{ctx: main.ctx, legend: js.Null(), data: make([]int, historySize)}
+}
+
+func newChart(document js.Value, historySize int) chart {
+	// all canvases have the same width and height
+	canvasPods := document.Call("getElementById", "canvas_pods")
+
+	c := chart{
+		pods:         newSubchart(document, "canvas_pods", historySize),
+		podsLoad:     newSubchart(document, "canvas_pod_cpu_usage", historySize),
+		podsMemory:   newSubchart(document, "canvas_pod_memory", historySize),
+		throttled:    newSubchart(document, "canvas_throttling", historySize),
+		restarts:     newSubchart(document, "canvas_restarts", historySize),
+		unmetLoad:    newSubchart(document, "canvas_unmet_cpu_load", historySize),
+		errorRate:    newSubchart(document, "canvas_error_rate", historySize),
+		nodes:        newSubchart(document, "canvas_nodes", historySize),
+		clusterCPU:   newSubchart(document, "canvas_cluster_utilization", historySize),
+		evictions:    newSubchart(document, "canvas_evictions", historySize),
+		workloads:    newSubchart(document, "canvas_workloads", historySize),
+		zones:        newSubchart(document, "canvas_zones", historySize),
+		canvasWidth:  canvasPods.Get("width").Int(),
+		canvasHeight: canvasPods.Get("height").Int(),
+	}
+
+	c.podsLoadMin = newHiddenSubchart(c.podsLoad, historySize)
+	c.podsLoadMax = newHiddenSubchart(c.podsLoad, historySize)
+	c.podsMemoryMin = newHiddenSubchart(c.podsMemory, historySize)
+	c.podsMemoryMax = newHiddenSubchart(c.podsMemory, historySize)
+
+	for status := range podStatusCount {
+		c.podsByStatus[status] = newHiddenSubchart(c.pods, historySize)
+	}
+	c.unschedulable = newHiddenSubchart(c.pods, historySize)
+	c.desired = newHiddenSubchart(c.pods, historySize)
+	c.clusterMemory = newHiddenSubchart(c.clusterCPU, historySize)
+	c.usageOfRequest = newHiddenSubchart(c.throttled, historySize)
+	for status := range nodeStatusCount {
+		c.nodesByStatus[status] = newHiddenSubchart(c.nodes, historySize)
+	}
+	for i := range maxWorkloads {
+		c.podsByWorkload[i] = newHiddenSubchart(c.workloads, historySize)
+	}
+	for z := range maxZones {
+		c.podsByZone[z] = newHiddenSubchart(c.zones, historySize)
+	}
+
+	// fill pods with 1 (only for replicas)
+	for i := range historySize {
+		c.pods.data[i] = 1
+		c.podsByStatus[podStatusReady].data[i] = 1
+		c.desired.data[i] = 1
+	}
+
+	return c
+}
+
+func drawCharts(c chart) {
+	const drawLabels = true
+
+	clearChart(c.pods.ctx, c)
+	{
+		lo, hi := findMinMax(c.pods.data)
+		_, hiDesired := findMinMax(c.desired.data)
+		hi = max(hi, hiDesired)
+		drawStackedStatus(c.pods.ctx, c, hi)
+		drawOneChart(c.pods.ctx, js.Null(), c, c.desired.data, "black", false, 1, lo, hi)
+		drawOneChart(c.pods.ctx, c.pods.legend, c, c.pods.data, "blue", drawLabels, 2, lo, hi)
+	}
+
+	clearChart(c.workloads.ctx, c)
+	{
+		lo, hi := findMinMax(c.workloads.data)
+		drawStackedWorkloads(c.workloads.ctx, c, hi)
+		drawOneChart(c.workloads.ctx, c.workloads.legend, c, c.workloads.data, "blue", drawLabels, 2, lo, hi)
+	}
+
+	clearChart(c.zones.ctx, c)
+	{
+		lo, hi := findMinMax(c.zones.data)
+		drawStackedZones(c.zones.ctx, c, hi)
+		drawOneChart(c.zones.ctx, c.zones.legend, c, c.zones.data, "blue", drawLabels, 2, lo, hi)
+	}
+
+	clearChart(c.nodes.ctx, c)
+	{
+		lo, hi := findMinMax(c.nodes.data)
+		drawStackedNodes(c.nodes.ctx, c, hi)
+		drawOneChart(c.nodes.ctx, c.nodes.legend, c, c.nodes.data, "blue", drawLabels, 2, lo, hi)
+	}
+
+	clearChart(c.clusterCPU.ctx, c)
+	{
+		lo, hi := findMinMax(c.clusterCPU.data)
+		_, hiMemory := findMinMax(c.clusterMemory.data)
+		hi = max(hi, hiMemory, 100)
+		drawOneChart(c.clusterCPU.ctx, js.Null(), c, c.clusterMemory.data, "red", false, 2, lo, hi)
+		drawOneChart(c.clusterCPU.ctx, c.clusterCPU.legend, c, c.clusterCPU.data, "blue", drawLabels, 2, lo, hi)
+	}
+
+	clearChart(c.evictions.ctx, c)
+	{
+		lo, hi := findMinMax(c.evictions.data)
+		drawOneChart(c.evictions.ctx, c.evictions.legend, c, c.evictions.data, "red", drawLabels, 2, lo, hi)
+	}
+
+	drawMinAvgMax(c, c.podsLoad, c.podsLoadMin, c.podsLoadMax)
+
+	clearChart(c.throttled.ctx, c)
+	{
+		lo, hi := findMinMax(c.throttled.data)
+		_, hiUsage := findMinMax(c.usageOfRequest.data)
+		hi = max(hi, hiUsage, 100)
+		drawOneChart(c.throttled.ctx, js.Null(), c, c.usageOfRequest.data, "blue", false, 2, lo, hi)
+		drawOneChart(c.throttled.ctx, c.throttled.legend, c, c.throttled.data, "red", drawLabels, 2, lo, hi)
+	}
+
+	drawMinAvgMax(c, c.podsMemory, c.podsMemoryMin, c.podsMemoryMax)
+
+	clearChart(c.restarts.ctx, c)
+	{
+		lo, hi := findMinMax(c.restarts.data)
+		drawOneChart(c.restarts.ctx, c.restarts.legend, c, c.restarts.data, "red", drawLabels, 2, lo, hi)
+	}
+
+	clearChart(c.unmetLoad.ctx, c)
+	{
+		lo, hi := findMinMax(c.unmetLoad.data)
+		drawOneChart(c.unmetLoad.ctx, c.unmetLoad.legend, c, c.unmetLoad.data, "blue", drawLabels, 2, lo, hi)
+	}
+
+	clearChart(c.errorRate.ctx, c)
+	{
+		lo, hi := findMinMax(c.errorRate.data)
+		drawOneChart(c.errorRate.ctx, c.errorRate.legend, c, c.errorRate.data, "red", drawLabels, 2, lo, hi)
+	}
+}
+
+// drawMinAvgMax draws the average line over the min/max band.
+func drawMinAvgMax(c chart, avg, lower, upper subchart) {
+	const drawLabels = true
+
+	clearChart(avg.ctx, c)
+
+	lo, _ := findMinMax(lower.data)
+	_, hi := findMinMax(upper.data)
+	drawBand(avg.ctx, c, lower.data, upper.data, "rgba(0, 0, 255, 0.2)", hi)
+	drawOneChart(avg.ctx, avg.legend, c, avg.data, "blue", drawLabels, 2, lo, hi)
+}
+
+// statusColors holds the colors for the pod status stacked areas,
+// listed from the bottom of the stack up.
+var statusColors = []struct {
+	status podStatus
+	color  string
+}{
+	{podStatusReady, "rgba(0, 0, 255, 0.3)"},
+	{podStatusNotReady, "rgba(0, 160, 0, 0.5)"},
+	{podStatusCrashLoopBackOff, "rgba(90, 90, 90, 0.6)"},
+	{podStatusContainerCreating, "rgba(255, 215, 0, 0.6)"},
+	{podStatusPending, "rgba(255, 140, 0, 0.6)"},
+	{podStatusTerminating, "rgba(255, 0, 0, 0.5)"},
+	{podStatusFailed, "rgba(128, 0, 128, 0.6)"},
+}
+
+// unschedulableColor is the color for pending pods no node has room for,
+// stacked right above the other pending pods.
+const unschedulableColor = "rgba(200, 0, 0, 0.8)"
+
+// statusColor returns the color used to draw the pod status.
+func statusColor(status podStatus) string {
+	for _, sc := range statusColors {
+		if sc.status == status {
+			return sc.color
+		}
+	}
+	return "gray"
+}
+
+// drawStackedStatus draws the number of pods in each status as stacked areas.
+func drawStackedStatus(ctx js.Value, c chart, maxValue int) {
+	lower := make([]int, len(c.pods.data))
+	stack := func(data []int, color string) {
+		upper := make([]int, len(lower))
+		for i, v := range data {
+			upper[i] = lower[i] + v
+		}
+		drawBand(ctx, c, lower, upper, color, maxValue)
+		lower = upper
+	}
+	for _, sc := range statusColors {
+		stack(c.podsByStatus[sc.status].data, sc.color)
+		if sc.status == podStatusPending {
+			stack(c.unschedulable.data, unschedulableColor)
+		}
+	}
+}
+
+// nodeStatusColors holds the colors for the node status stacked areas,
+// listed from the bottom of the stack up.
+var nodeStatusColors = []struct {
+	status nodeStatus
+	color  string
+}{
+	{nodeStatusReady, "rgba(0, 0, 255, 0.3)"},
+	{nodeStatusProvisioning, "rgba(255, 215, 0, 0.6)"},
+	{nodeStatusDraining, "rgba(255, 0, 0, 0.5)"},
+	{nodeStatusNotReady, "rgba(90, 90, 90, 0.6)"},
+}
+
+// drawStackedNodes draws the number of nodes in each status as stacked areas.
+func drawStackedNodes(ctx js.Value, c chart, maxValue int) {
+	lower := make([]int, len(c.nodes.data))
+	for _, sc := range nodeStatusColors {
+		upper := make([]int, len(lower))
+		for i, v := range c.nodesByStatus[sc.status].data {
+			upper[i] = lower[i] + v
+		}
+		drawBand(ctx, c, lower, upper, sc.color, maxValue)
+		lower = upper
+	}
+}
+
+// drawStackedWorkloads draws the number of pods of each workload as stacked areas.
+func drawStackedWorkloads(ctx js.Value, c chart, maxValue int) {
+	lower := make([]int, len(c.workloads.data))
+	for i, color := range workloadColors {
+		upper := make([]int, len(lower))
+		for j, v := range c.podsByWorkload[i].data {
+			upper[j] = lower[j] + v
+		}
+		drawBand(ctx, c, lower, upper, color, maxValue)
+		lower = upper
+	}
+}
+
+// drawStackedZones draws the ready pods in each zone as stacked areas.
+func drawStackedZones(ctx js.Value, c chart, maxValue int) {
+	lower := make([]int, len(c.zones.data))
+	for z, color := range zoneColors {
+		upper := make([]int, len(lower))
+		for i, v := range c.podsByZone[z].data {
+			upper[i] = lower[i] + v
+		}
+		drawBand(ctx, c, lower, upper, color, maxValue)
+		lower = upper
+	}
+}
+
+// drawBand fills the area between the lower and upper series.
+func drawBand(ctx js.Value, c chart, lower, upper []int, color string, maxValue int) {
+	// avoid division by zero
+	if maxValue <= 0 {
+		maxValue = 1
+	}
+
+	ctx.Set("fillStyle", color)
+	ctx.Call("beginPath")
+
+	// left to right along the upper series
+	for i, v := range upper {
+		x := i * c.canvasWidth / len(upper)
+		y := c.canvasHeight - (v * c.canvasHeight / maxValue) // invert y axis
+		if i == 0 {
+			ctx.Call("moveTo", x, y)
+		} else {
+			ctx.Call("lineTo", x, y)
+		}
+	}
+
+	// right to left along the lower series
+	for i := len(lower) - 1; i >= 0; i-- {
+		x := i * c.canvasWidth / len(lower)
+		y := c.canvasHeight - (lower[i] * c.canvasHeight / maxValue) // invert y axis
+		ctx.Call("lineTo", x, y)
+	}
+
+	ctx.Call("closePath")
+	ctx.Call("fill")
+}
+
+func findMinMax(data []int) (int, int) {
+	maxPods := 0
+	minPods := math.MaxInt // NaN
+	for _, v := range data {
+		if v > maxPods {
+			maxPods = v
+		}
+		if v < minPods {
+			minPods = v
+		}
+	}
+	return minPods, maxPods
+}
+
+func clearChart(ctx js.Value, c chart) {
+	ctx.Set("fillStyle", "white")
+	ctx.Call("fillRect", 0, 0, c.canvasWidth, c.canvasHeight)
+}
+
+func drawOneChart(ctx, legend js.Value, c chart, data []int,
+	color string, drawLabels bool,
+	width,
+	minPods, maxPods int) {
+
+	// pod space x ranges from 0 to len(c.pods)
+	// pod space y ranges from 0 to maxPods
+	// canvas space x ranges from 0 to c.canvasWidth
+	// canvas space y ranges from 0 to c.canvasHeight
+
+	// draw line
+	ctx.Set("strokeStyle", color)
+	ctx.Set("lineWidth", width)
+	ctx.Call("beginPath")
+
+	// avoid division by zero
+	if maxPods <= 0 {
+		maxPods = 1
+	}
+
+	for i, v := range data {
+		// map pod space to canvas space
+		x := i * c.canvasWidth / len(data)
+		y := c.canvasHeight - (v * c.canvasHeight / maxPods) // invert y axis
+
+		if i == 0 {
+			ctx.Call("moveTo", x, y)
+		} else {
+			ctx.Call("lineTo", x, y)
+		}
+	}
+	ctx.Call("stroke")
+
+	if drawLabels {
+		// Draw a label for max replicas at top-left corner
+		labelText := fmt.Sprintf("Max: %d", maxPods)
+		ctx.Set("font", "16px Arial")
+		ctx.Set("fillStyle", "black")
+		ctx.Call("fillText", labelText, 10, 20)
+
+		// Draw a label for latest replicas count at right size
+		// But vertically aligned with the last point
+		latestReplicas := data[len(data)-1]
+
+		labelText = fmt.Sprintf("Cur: %d", latestReplicas)
+		textMetrics := ctx.Call("measureText", labelText)
+		textWidth := textMetrics.Get("width").Float()
+
+		x := c.canvasWidth - int(textWidth) - 5
+		y := c.canvasHeight - (latestReplicas * c.canvasHeight / maxPods)
+		// Move y slight up to avoid overlapping with the line
+		y -= 10
+		// Adjust y to avoid drawing outside canvas
+		if y < 20 {
+			y = 20
+		}
+		if y > c.canvasHeight-10 {
+			y = c.canvasHeight - 10
+		}
+
+		ctx.Call("fillText", labelText, x, y)
+
+		// Draw label with min, max, current replicas into legend element
+		if !legend.IsNull() {
+			var minPodsStr string
+			if minPods == math.MaxInt {
+				minPodsStr = "N/A"
+			} else {
+				minPodsStr = fmt.Sprintf("%d", minPods)
+			}
+
+			// Update specific elements instead of replacing innerHTML
+			legend.Call("querySelector", ".legend-min").Set("innerText", minPodsStr)
+			legend.Call("querySelector", ".legend-max").Set("innerText", fmt.Sprintf("%d", maxPods))
+			legend.Call("querySelector", ".legend-current").Set("innerText", fmt.Sprintf("%d", latestReplicas))
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// node is a cluster node pods are scheduled on.
+type node struct {
+	id              int
+	cpu             float64   // allocatable mCores
+	memory          float64   // allocatable MiB
+	cpuRequested    float64   // mCores requested by the pods bound to the node
+	memoryRequested float64   // MiB requested by the pods bound to the node
+	unlimited       int       // pods bound to the node without a CPU limit
+	readyAt         time.Time // provisioning until then
+	unneededSince   time.Time // zero unless the autoscaler finds the node unneeded
+	draining        bool      // being removed: pods evicted, no new pods
+	cordoned        bool      // drained for maintenance: pods evicted subject to their budgets, no new pods
+	instanceType    string    // empty for node group nodes, see karpenter
+	price           float64   // per hour, for provisioned instances
+	replacement     int       // id of the node replacing this one, 0 if none
+	interruptAt     time.Time // spot interruption deadline, zero if none
+	zone            int       // availability zone, see zoneName
+	unreachable     bool      // its zone is down: NotReady, pods lost
+}
+
+// nodeStatus is the node status shown in the node count chart.
+type nodeStatus int
+
+const (
+	nodeStatusReady        nodeStatus = iota // accepting pods
+	nodeStatusProvisioning                   // added, but not ready yet
+	nodeStatusDraining                       // pods evicted, to be removed, maintained or interrupted
+	nodeStatusNotReady                       // unreachable, its zone is down
+	nodeStatusCount                          // number of statuses, not a status
+)
+
+func (n node) status(now time.Time) nodeStatus {
+	switch {
+	case n.unreachable:
+		return nodeStatusNotReady
+	case n.draining, n.cordoned, !n.interruptAt.IsZero():
+		return nodeStatusDraining
+	case now.Before(n.readyAt):
+		return nodeStatusProvisioning
+	}
+	return nodeStatusReady
+}
+
+// utilization returns the fraction of the node allocatable resources
+// requested by its pods, the highest of CPU and memory.
+func (n node) utilization() float64 {
+	return max(n.cpuRequested/n.cpu, n.memoryRequested/n.memory)
+}
+
+// fits tells whether the node has room for the requests.
+func (n node) fits(cpuRequest, memoryRequest float64) bool {
+	return n.cpuRequested+cpuRequest <= n.cpu && n.memoryRequested+memoryRequest <= n.memory
+}
+
+// podScheduling is where the scheduler could place a pod, or why not.
+type podScheduling struct {
+	priority      int
+	unschedulable bool // no node has room for the pod requests
+	zoneBlocked   bool // only zones down satisfy the topology spread constraint
+	nominated     int  // id of the node where the pod preempted lower priority pods
+}
+
+// cluster holds the nodes the pods of its workloads are scheduled on.
+type cluster struct {
+	workloads   []*deployment
+	nodes       []node
+	nodeCPU     float64 // allocatable mCores of every node
+	nodeMemory  float64 // allocatable MiB of every node
+	lastNodeID  int
+	evictions   int // pods evicted from drained nodes
+	preemptions int // pods preempted by higher priority pods
+	events      eventLog
+	zones       int                 // availability zones the nodes are spread across
+	outageUntil [maxZones]time.Time // end of the latest outage of every zone
+	zoneDown    [maxZones]bool      // zones in an outage now
+	drainRetry  time.Time           // last retry of the evictions on the draining nodes
+}
+
+// resize sets the number of node group nodes and their allocatable resources.
+// Missing nodes are added ready. Extra nodes are removed from the end
+// of the list, unless keepExtra is set: with the cluster autoscaler,
+// count is the minimum and the autoscaler removes the extra nodes.
+// Instances provisioned by karpenter are left alone.
+func (c *cluster) resize(count int, cpu, memory float64, keepExtra bool, now time.Time) {
+	c.nodeCPU = cpu
+	c.nodeMemory = memory
+
+	var group int
+	c.nodes = slices.DeleteFunc(c.nodes, func(n node) bool {
+		if n.instanceType != "" {
+			return false
+		}
+		group++
+		return group > count && !keepExtra
+	})
+	for ; group < count; group++ {
+		c.addNode(now)
+	}
+
+	for i, n := range c.nodes {
+		if n.instanceType == "" {
+			c.nodes[i].cpu = cpu
+			c.nodes[i].memory = memory
+		}
+	}
+}
+
+// addNode adds a node which becomes ready at readyAt.
+func (c *cluster) addNode(readyAt time.Time) {
+	c.lastNodeID++
+	c.nodes = append(c.nodes, node{
+		id:      c.lastNodeID,
+		cpu:     c.nodeCPU,
+		memory:  c.nodeMemory,
+		readyAt: readyAt,
+		zone:    c.pickZone(),
+	})
+}
+
+// pods returns the pods of all workloads, to be inspected or modified in place.
+func (c *cluster) pods() []*pod {
+	var list []*pod
+	for _, w := range c.workloads {
+		for i := range w.podList {
+			list = append(list, &w.podList[i])
+		}
+	}
+	return list
+}
+
+// findNode returns the node with the given id, or nil if there is none.
+func (c *cluster) findNode(id int) *node {
+	for i := range c.nodes {
+		if c.nodes[i].id == id {
+			return &c.nodes[i]
+		}
+	}
+	return nil
+}
+
+// holdsResources tells whether the pod requests count against its node.
+// Failed pods have no running containers, so they release their node.
+func (p pod) holdsResources() bool {
+	return p.node != 0 && p.status != podStatusFailed
+}
+
+// reserve recomputes the resources requested on each node
+// by the pods bound to it, and counts the pods without a CPU limit.
+// Placeholders are pause containers, they burn no CPU.
+func (c *cluster) reserve() {
+	for i := range c.nodes {
+		c.nodes[i].cpuRequested = 0
+		c.nodes[i].memoryRequested = 0
+		c.nodes[i].unlimited = 0
+	}
+	for _, p := range c.pods() {
+		if !p.holdsResources() {
+			continue
+		}
+		if n := c.findNode(p.node); n != nil {
+			n.cpuRequested += p.cpuRequest
+			n.memoryRequested += p.memoryRequest
+			if p.cpuLimit <= 0 && p.priority != placeholderPriority {
+				n.unlimited++
+			}
+		}
+	}
+}
+
+// release returns the pod requests to its node.
+func (c *cluster) release(p pod) {
+	if !p.holdsResources() {
+		return
+	}
+	if n := c.findNode(p.node); n != nil {
+		n.cpuRequested -= p.cpuRequest
+		n.memoryRequested -= p.memoryRequest
+	}
+}
+
+// bind assigns the pod to the first ready node with room for its requests,
+// leaving room for the higher priority pods nominated to the node.
+// Zones, if not nil, are tried in order, see spreadZones.
+// It returns false when no node fits the pod: the pod is unschedulable.
+func (c *cluster) bind(p *pod, zones []int, now time.Time) bool {
+	if zones == nil {
+		return c.bindZone(p, -1, now)
+	}
+	for _, z := range zones {
+		if c.bindZone(p, z, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindZone assigns the pod to the first ready node of the zone,
+// or of any zone if negative, with room for its requests.
+func (c *cluster) bindZone(p *pod, zone int, now time.Time) bool {
+	for i, n := range c.nodes {
+		if n.status(now) != nodeStatusReady || (zone >= 0 && n.zone != zone) {
+			continue
+		}
+		cpu, memory := c.nominatedRequests(n.id, p.priority)
+		if n.fits(p.cpuRequest+cpu, p.memoryRequest+memory) {
+			c.nodes[i].cpuRequested += p.cpuRequest
+			c.nodes[i].memoryRequested += p.memoryRequest
+			p.node = n.id
+			return true
+		}
+	}
+	return false
+}
+
+// evictLost fails the pods bound to nodes removed from the cluster:
+// their containers are gone along with the node.
+func (c *cluster) evictLost(now time.Time) {
+	for _, p := range c.pods() {
+		if p.node == 0 || c.findNode(p.node) != nil {
+			continue
+		}
+		p.node = 0
+		if p.status != podStatusFailed {
+			p.setStatus(podStatusFailed, now)
+		}
+	}
+}
+
+// countByStatus returns the number of nodes in each status.
+func (c *cluster) countByStatus(now time.Time) [nodeStatusCount]int {
+	var count [nodeStatusCount]int
+	for _, n := range c.nodes {
+		count[n.status(now)]++
+	}
+	return count
+}
+
+// requestedPercent returns the percentage of the allocatable CPU and memory
+// of ready nodes requested by pods.
+func (c *cluster) requestedPercent(now time.Time) (cpu, memory float64) {
+	var cpuAllocatable, memoryAllocatable float64
+	for _, n := range c.nodes {
+		if n.status(now) != nodeStatusReady {
+			continue
+		}
+		cpuAllocatable += n.cpu
+		memoryAllocatable += n.memory
+		cpu += n.cpuRequested
+		memory += n.memoryRequested
+	}
+	if cpuAllocatable <= 0 || memoryAllocatable <= 0 {
+		return 0, 0
+	}
+	return 100 * cpu / cpuAllocatable, 100 * memory / memoryAllocatable
+}
+
+// countUnschedulable returns the number of pending pods no node has room for.
+func (d *deployment) countUnschedulable() int {
+	var count int
+	for _, p := range d.podList {
+		if p.unschedulable {
+			count++
+		}
+	}
+	return count
+}
+
+// schedule binds the pending pod to a node and moves it to ContainerCreating.
+// A pod that found no room when it was due is bound as soon as a node
+// has room for it, hence it starts creating now rather than when it was due.
+// A pod no node has room for may preempt pods of lower priority,
+// unless the preemption policy of the deployment is Never.
+// It returns false when the pod stays pending.
+func (d *deployment) schedule(p *pod, when, now time.Time) bool {
+	zones := d.spreadZones()
+	if !d.cluster.bind(p, zones, now) {
+		p.unschedulable = true
+		p.zoneBlocked = zones != nil && d.cluster.allDown(zones)
+		if d.preemptLower {
+			d.cluster.preempt(p, d, now)
+		}
+		return false
+	}
+	d.zonePods[d.cluster.findNode(p.node).zone]++
+	p.zoneBlocked = false
+	p.nominated = 0
+	if p.unschedulable {
+		p.unschedulable = false
+		when = now
+	}
+	p.setStatus(podStatusContainerCreating, when)
+	return true
+}
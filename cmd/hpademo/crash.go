@@ -0,0 +1,81 @@
+package main
+
+import "time"
+
+const (
+	crashLoopInitialBackoff = 10 * time.Second
+	crashLoopMaxBackoff     = 5 * time.Minute
+
+	// crashLoopBackoffReset is how long a container must run
+	// without crashing for the kubelet to reset the restart backoff.
+	crashLoopBackoffReset = 10 * time.Minute
+)
+
+// podFailures makes the containers of the pods fail: they crash during
+// startup or while ready, and fail their probes while saturated.
+type podFailures struct {
+	readinessFail  time.Duration // saturation time that fails the readiness probe, 0 disables
+	livenessFail   time.Duration // saturation time that fails the liveness probe, 0 disables
+	startupFailure float64       // probability of a container failing during startup
+	crashRate      float64       // container crashes per hour while ready
+}
+
+// containerState is the pod container as the kubelet sees it.
+type containerState struct {
+	containerStarted time.Time
+	warmSince        time.Time // first ready since the container started
+	restarts         int
+	startupFails     bool          // container will crash before becoming ready
+	crashes          int           // consecutive crashes, for the restart backoff
+	backoff          time.Duration // current CrashLoopBackOff delay
+	saturatedSince   time.Time     // zero if not saturated at the CPU limit
+	readinessFailed  bool
+}
+
+// crashLoopBackoff returns the kubelet restart delay after the given number
+// of consecutive crashes: 10s, 20s, 40s ... capped at 5m.
+func crashLoopBackoff(crashes int) time.Duration {
+	backoff := crashLoopInitialBackoff
+	for range crashes - 1 {
+		backoff *= 2
+		if backoff >= crashLoopMaxBackoff {
+			return crashLoopMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// startContainer starts the pod container, which then goes through the
+// startup path (readiness initial delay). The container may be doomed to
+// fail during startup, according to the startup failure probability.
+func (d *deployment) startContainer(p *pod, when time.Time) {
+	p.containerStarted = when
+	p.startupFails = d.rng.Float64() < d.startupFailure
+	p.setStatus(podStatusNotReady, when)
+}
+
+// crashContainer kills the pod container. The kubelet restarts it
+// after the CrashLoopBackOff delay, the pod is not ready meanwhile.
+func (d *deployment) crashContainer(p *pod, when time.Time) {
+	if when.Sub(p.containerStarted) >= crashLoopBackoffReset {
+		p.crashes = 0
+	}
+	p.crashes++
+	p.restarts++
+	d.restarts++
+	p.backoff = crashLoopBackoff(p.crashes)
+	p.readinessFailed = false
+	p.saturatedSince = time.Time{}
+	p.setStatus(podStatusCrashLoopBackOff, when)
+}
+
+// randomCrash crashes a ready pod according to the crash rate.
+// The simulation runs one step per second.
+func (d *deployment) randomCrash(p *pod, now time.Time) {
+	if p.status != podStatusReady || d.crashRate <= 0 {
+		return
+	}
+	if d.rng.Float64() < d.crashRate/3600 {
+		d.crashContainer(p, now)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCrashLoopBackoffDoublesUpToCap(t *testing.T) {
+	d := &deployment{}
+	p := &pod{}
+	when := testNow
+
+	var prev time.Duration
+	for crash := 1; crash <= 10; crash++ {
+		p.containerStarted = when
+		when = when.Add(time.Second) // crashes right after starting
+		d.crashContainer(p, when)
+
+		switch {
+		case crash == 1 && p.backoff != crashLoopInitialBackoff:
+			t.Fatalf("first backoff %v, want %v", p.backoff, crashLoopInitialBackoff)
+		case p.backoff > crashLoopMaxBackoff:
+			t.Fatalf("crash %d: backoff %v above the cap %v", crash, p.backoff, crashLoopMaxBackoff)
+		case crash > 1 && p.backoff != min(2*prev, crashLoopMaxBackoff):
+			t.Fatalf("crash %d: backoff %v after %v, want it doubled or capped", crash, p.backoff, prev)
+		}
+		prev = p.backoff
+	}
+	if p.backoff != crashLoopMaxBackoff {
+		t.Errorf("backoff %v after 10 crashes, want the cap %v", p.backoff, crashLoopMaxBackoff)
+	}
+	if p.restarts != 10 || d.restarts != 10 {
+		t.Errorf("restarts pod=%d deployment=%d, want 10", p.restarts, d.restarts)
+	}
+}
+
+func TestCrashLoopBackoffResetsAfterRunningLong(t *testing.T) {
+	d := &deployment{}
+	p := &pod{}
+	for range 5 {
+		p.containerStarted = testNow
+		d.crashContainer(p, testNow.Add(time.Second))
+	}
+
+	p.containerStarted = testNow
+	d.crashContainer(p, testNow.Add(crashLoopBackoffReset))
+	if p.backoff != crashLoopInitialBackoff {
+		t.Errorf("backoff %v after running %v, want it reset to %v",
+			p.backoff, crashLoopBackoffReset, crashLoopInitialBackoff)
+	}
+	if p.status != podStatusCrashLoopBackOff {
+		t.Errorf("status %v, want CrashLoopBackOff", p.status)
+	}
+}
@@ -1,29 +1,260 @@
 package main
 
 import (
+	"math"
+	"math/rand/v2"
+	"slices"
 	"time"
 )
 
 type deployment struct {
+	name            string
+	kind            workloadKind
+	podManagement   podManagementPolicy
 	podList         []pod
 	desiredReplicas int
-	startupTime     time.Duration
-	stopTime        time.Duration
+	podTemplate
+	podTimings
+	coldStart
+	podFailures
+	memoryModel
+	rolloutState
+	priorityClass
+	scaleUpTracker
+	quota         resourceQuota // quota of the workload namespace new pods must fit in
+	failedCreates int           // pod creations rejected by the quota
+	pdb           podDisruptionBudget
+	pdbBlocked    int // evictions the disruption budget refused
+	spread        topologySpread
+	zonePods      [maxZones]int // pods counted by the topology spread constraint in every zone
+	vpa           verticalPodAutoscaler
+	cluster       *cluster // nodes the pods are scheduled on
+	restarts      int      // container restarts of all its pods
+	lastPodID     int
+	rng           *rand.Rand
+	deleted       []pod // recently deleted pods, kept for the timeline
 }
 
+// podTemplate is the resources of the pod spec, and how the kubelet enforces them.
+type podTemplate struct {
+	cpuRequest    float64       // mCores requested by the pod template
+	memoryRequest float64       // MiB requested by the pod template
+	cpuLimit      float64       // mCores limit of the pod template
+	memoryLimit   float64       // MiB, containers above it are OOMKilled, 0 disables
+	burstiness    float64       // spread of the load across the CFS periods of a second (0.3 = ±30%)
+	resizeDelay   time.Duration // time taken by the kubelet to resize a running pod in place
+}
+
+// podTimings is how long a pod stays in every status.
+type podTimings struct {
+	schedulingTime time.Duration // Pending
+	imagePullTime  time.Duration // ContainerCreating
+	startupTime    time.Duration // Running, not Ready: readiness probe initial delay
+	startupDist    startupDistribution
+	startupSpread  float64       // relative spread of image pull and startup times
+	preStopTime    time.Duration // Terminating: preStop hook
+	stopTime       time.Duration // Terminating: application shutdown after SIGTERM
+	gracePeriod    time.Duration // Terminating: SIGKILL after terminationGracePeriodSeconds
+}
+
+// coldStart is how new pods take traffic and burn CPU until they are warm.
+type coldStart struct {
+	slowStart   time.Duration // traffic weight ramp-up window for new pods
+	coldPenalty float64       // extra CPU per request for a cold pod (0.5 = 50%)
+	warmUpTime  time.Duration // time for a cold pod to become fully warm
+	startupCPU  float64       // mCores burnt by a starting pod while it boots
+}
+
+// maxDeletedPods limits how many deleted pods are kept for the timeline.
+const maxDeletedPods = 200
+
 type pod struct {
+	id               int
 	status           podStatus
 	lastStatusChange time.Time
+	node             int // id of the node the pod is bound to, 0 if not scheduled
+	revision         int // ReplicaSet generation the pod belongs to
+	ordinal          int // StatefulSet pod ordinal, -1 for Deployment pods
+	podLifetime
+	podUsage
+	containerState
+	podAllocation
+	podScheduling
+}
+
+// podLifetime records when the pod went through its statuses.
+type podLifetime struct {
+	created       time.Time
+	imagePullTime time.Duration
+	startupTime   time.Duration
+	readySince    time.Time
+	firstReady    time.Time
+	transitions   []podTransition // latest status changes, for the timeline
+	deletedAt     time.Time
+}
+
+// podUsage is what the pod used in the last second.
+type podUsage struct {
+	cpuUsage      float64 // mCores used in the last second
+	load          float64 // load served in the last second
+	memory        float64 // MiB used by the container
+	throttled     float64 // fraction of the CFS periods throttled in the last second
+	throttleDelay time.Duration
+}
+
+// podTransition records a pod entering a status.
+type podTransition struct {
+	status podStatus
+	at     time.Time
+}
+
+// maxPodTransitions limits how many status changes are kept per pod.
+const maxPodTransitions = 32
+
+// newPod creates a pending pod, drawing its image pull and startup times
+// from the startup distribution.
+func (d *deployment) newPod(now time.Time) pod {
+	d.lastPodID++
+	cpuRequest, memoryRequest, cpuLimit := d.podRequests()
+	p := pod{
+		id:               d.lastPodID,
+		status:           podStatusPending,
+		lastStatusChange: now,
+		ordinal:          -1,
+		podLifetime: podLifetime{
+			created:       now,
+			imagePullTime: sampleDuration(d.rng, d.startupDist, d.imagePullTime, d.startupSpread),
+			startupTime:   sampleDuration(d.rng, d.startupDist, d.startupTime, d.startupSpread),
+			transitions:   []podTransition{{status: podStatusPending, at: now}},
+		},
+		podAllocation: podAllocation{
+			cpuRequest:    cpuRequest,
+			memoryRequest: memoryRequest,
+			cpuLimit:      cpuLimit,
+		},
+		podScheduling: podScheduling{priority: d.priority},
+	}
+	return p
+}
+
+// setStatus moves the pod into a new status at the given time.
+func (p *pod) setStatus(status podStatus, when time.Time) {
+	p.status = status
+	p.lastStatusChange = when
+
+	p.transitions = append(p.transitions, podTransition{status: status, at: when})
+	if extra := len(p.transitions) - maxPodTransitions; extra > 0 {
+		p.transitions = slices.Delete(p.transitions, 0, extra)
+	}
+
+	if status == podStatusReady {
+		p.readySince = when
+		if p.firstReady.IsZero() {
+			p.firstReady = when
+		}
+		if !p.readinessFailed {
+			p.warmSince = when // fresh container
+		}
+		p.readinessFailed = false
+	}
+}
+
+// trafficWeight returns the share of traffic the load balancer sends to the pod,
+// relative to a fully warmed up pod. During the slow start window the weight
+// ramps linearly from 0 to 1.
+func (p pod) trafficWeight(now time.Time, slowStart time.Duration) float64 {
+	if slowStart <= 0 {
+		return 1
+	}
+	ramp := float64(now.Sub(p.readySince)) / float64(slowStart)
+	return min(max(ramp, 0), 1)
+}
+
+// cpuCostFactor returns how much CPU the pod spends per request,
+// relative to a warm pod. A cold pod pays the full coldPenalty,
+// which decays linearly to zero over warmUpTime (e.g. JIT warm-up).
+func (p pod) cpuCostFactor(now time.Time, coldPenalty float64, warmUpTime time.Duration) float64 {
+	if coldPenalty <= 0 || warmUpTime <= 0 {
+		return 1
+	}
+	warmth := min(float64(now.Sub(p.warmSince))/float64(warmUpTime), 1)
+	return 1 + coldPenalty*(1-warmth)
 }
 
 type podStatus int
 
 const (
-	podStatusStarting podStatus = iota
-	podStatusRunning
-	podStatusTerminating
+	podStatusPending           podStatus = iota // waiting to be scheduled
+	podStatusContainerCreating                  // pulling image, creating container
+	podStatusNotReady                           // running, readiness probe not passed yet
+	podStatusReady                              // running and serving traffic
+	podStatusTerminating                        // running preStop hook and shutting down
+	podStatusFailed                             // killed at the end of the grace period
+	podStatusCrashLoopBackOff                   // container crashed, waiting to restart
+	podStatusCount                              // number of statuses, not a status
 )
 
+// podStatusDeleted is not a real status, it marks pods gone from the deployment.
+const podStatusDeleted podStatus = -1
+
+// failedPodRetention is how long failed pods are kept before garbage collection.
+const failedPodRetention = 15 * time.Second
+
+// nextStatus returns the status the pod moves to, and how long after entering
+// its current status. ok is false for statuses the pod only leaves when told to.
+func (d *deployment) nextStatus(p pod) (next podStatus, after time.Duration, ok bool) {
+	switch p.status {
+	case podStatusPending:
+		return podStatusContainerCreating, d.schedulingTime, true
+	case podStatusContainerCreating:
+		return podStatusNotReady, p.imagePullTime, true
+	case podStatusNotReady:
+		if p.readinessFailed {
+			return podStatusReady, readinessRecoveryTime, true
+		}
+		if p.startupFails {
+			return podStatusCrashLoopBackOff, p.startupTime, true
+		}
+		return podStatusReady, p.startupTime, true
+	case podStatusCrashLoopBackOff:
+		return podStatusNotReady, p.backoff, true
+	case podStatusTerminating:
+		// SIGKILL at the end of the grace period, the pod is deleted anyway
+		return podStatusDeleted, min(d.preStopTime+d.stopTime, d.gracePeriod), true
+	case podStatusFailed:
+		return podStatusDeleted, failedPodRetention, true
+	}
+	return p.status, 0, false
+}
+
+// advance moves the pod through its lifecycle up to now.
+// A pod may go through several statuses in one call.
+// It returns false when the pod is gone.
+func (d *deployment) advance(p *pod, now time.Time) bool {
+	for {
+		next, after, ok := d.nextStatus(*p)
+		if !ok || now.Sub(p.lastStatusChange) < after {
+			return true
+		}
+		when := p.lastStatusChange.Add(after)
+		switch {
+		case next == podStatusDeleted:
+			p.deletedAt = when
+			return false
+		case next == podStatusContainerCreating:
+			if !d.schedule(p, when, now) {
+				return true // stays pending
+			}
+		case next == podStatusCrashLoopBackOff:
+			d.crashContainer(p, when)
+		case next == podStatusNotReady && !p.readinessFailed:
+			d.startContainer(p, when)
+		default:
+			p.setStatus(next, when)
+		}
+	}
+}
+
 func (d *deployment) getReplicas() int {
 	return len(d.podList)
 }
@@ -38,88 +269,227 @@ func (d *deployment) countStatus(status podStatus) int {
 	return count
 }
 
-func (d *deployment) getRunning() int {
-	return d.countStatus(podStatusRunning)
-}
-
-func (d *deployment) getStarting() int {
-	return d.countStatus(podStatusStarting)
-}
-
-func (d *deployment) getStopping() int {
-	return d.countStatus(podStatusTerminating)
+// countByStatus returns the number of pods in each status.
+func (d *deployment) countByStatus() [podStatusCount]int {
+	var count [podStatusCount]int
+	for _, p := range d.podList {
+		count[p.status]++
+	}
+	return count
 }
 
 func (d *deployment) scale(replicas int) {
+	if replicas > d.desiredReplicas && d.scaleUpStart.IsZero() {
+		d.scaleUpStart = time.Now()
+	}
 	d.desiredReplicas = replicas
 }
 
-/*
-func (d *deployment) log(label string) {
-	fmt.Printf("%s: pods:%d run:%d start:%d stop:%d\n",
-		label,
-		d.getReplicas(), d.getRunning(), d.getStarting(),
-		d.getStopping())
-}
-*/
-
 func (d *deployment) update() {
 	var newPodList []pod
 
-	var running int
+	now := time.Now()
 
-	//d.log("before")
+	d.cluster.evictLost(now)
+	d.cluster.reserve()
+	d.countZonePods()
 
 	for _, p := range d.podList {
-		switch p.status {
-		case podStatusTerminating:
-			if elap := time.Since(p.lastStatusChange); elap < d.stopTime {
-				newPodList = append(newPodList, p) // preserve pod
-			}
-			//fmt.Println("preserved stopping")
-		case podStatusStarting:
-			if elap := time.Since(p.lastStatusChange); elap > d.startupTime {
-				// promote to running
-				p.status = podStatusRunning
-				p.lastStatusChange = time.Now()
-				//fmt.Println("promoted to running")
-			}
-			newPodList = append(newPodList, p)
-		case podStatusRunning:
-			newPodList = append(newPodList, p)
-			running++
+		if !d.advance(&p, now) {
+			d.cluster.release(p)
+			d.recordDeleted(p)
+			continue // pod is gone
 		}
+		d.probe(&p, now)
+		d.randomCrash(&p, now)
+		newPodList = append(newPodList, p)
 	}
 
-	// remove running PODs
-	removePods := running - d.desiredReplicas
-	for i, p := range newPodList {
-		if removePods < 1 {
-			break
+	d.initReplicaSets()
+	d.removeOrphans(newPodList, now)
+
+	if d.kind == kindStatefulSet {
+		newPodList = d.reconcileStatefulSet(newPodList, now)
+		d.syncRevisions(newPodList, now)
+	} else {
+		d.syncReplicaSets(newPodList, now)
+		for _, rs := range d.replicaSets {
+			newPodList = d.reconcile(newPodList, rs, now)
+		}
+		d.removeOldReplicaSets(newPodList, now)
+	}
+
+	d.podList = newPodList
+
+	d.runVPA(now)
+	d.runResizes(now)
+
+	d.trackScaleUp(d.countStatus(podStatusReady), now)
+}
+
+// reconcile brings the active pods of the ReplicaSet to its replicas,
+// like the ReplicaSet controller: surplus pods are deleted by rank,
+// and missing pods are created in slow-start batches. Terminating
+// and failed pods are not active, hence they are replaced right away.
+// It returns the updated pods.
+func (d *deployment) reconcile(pods []pod, rs replicaSet, now time.Time) []pod {
+	var active []int // indices into pods
+	for i, p := range pods {
+		if p.revision != rs.revision || p.ordinal >= 0 {
+			continue // not owned by the ReplicaSet
+		}
+		if p.status != podStatusTerminating && p.status != podStatusFailed {
+			active = append(active, i)
+		}
+	}
+
+	if surplus := len(active) - rs.replicas; surplus > 0 {
+		return d.deleteRanked(pods, active, min(surplus, burstReplicas), now)
+	}
+	return d.createBatches(pods, rs, min(rs.replicas-len(active), burstReplicas), now)
+}
+
+// recordDeleted keeps the deleted pod for the timeline.
+func (d *deployment) recordDeleted(p pod) {
+	d.deleted = append(d.deleted, p)
+	if extra := len(d.deleted) - maxDeletedPods; extra > 0 {
+		d.deleted = slices.Delete(d.deleted, 0, extra)
+	}
+}
+
+// removeDeleted removes the pods deleted on the spot, releasing
+// their resources on the node.
+func (d *deployment) removeDeleted() {
+	d.podList = slices.DeleteFunc(d.podList, func(p pod) bool {
+		if p.deletedAt.IsZero() {
+			return false
+		}
+		d.cluster.release(p)
+		d.recordDeleted(p)
+		return true
+	})
+}
+
+// serveLoad distributes the offered load among the running pods, each one
+// capped at its CPU ceiling, and returns the load actually served.
+// Pods running but not ready yet take no load, but burn startupCPU
+// (capped at their CPU ceiling) while they boot.
+// Pods with a CPU limit are throttled by the CFS quota when the bursts
+// of their load exceed it, serving less than assigned.
+// Pods serving at their CPU ceiling are marked as saturated.
+//
+// Load is expressed as the CPU a warm pod would need to serve it.
+// A cold pod needs more CPU for the same load, hence it serves less
+// load before hitting its CPU ceiling.
+func (d *deployment) serveLoad(b *balancer, offered float64) float64 {
+	now := time.Now()
+
+	var serving []int
+	var capacity, weight, costFactor []float64
+	ceiling := make([]float64, len(d.podList))
+	for i, p := range d.podList {
+		d.podList[i].cpuUsage = 0
+		d.podList[i].load = 0
+		d.podList[i].throttled = 0
+		d.podList[i].throttleDelay = 0
+		ceiling[i] = d.cluster.cpuCeiling(p)
+		if d.isBooting(p) {
+			d.podList[i].cpuUsage = min(d.startupCPU, ceiling[i])
 		}
-		if p.status == podStatusRunning {
-			// switch pod to terminating
-			p.status = podStatusTerminating
-			p.lastStatusChange = time.Now()
-			newPodList[i] = p
-			removePods--
-			//fmt.Println("promoted to terminating")
+		if p.status == podStatusReady {
+			f := p.cpuCostFactor(now, d.coldPenalty, d.warmUpTime)
+			serving = append(serving, i)
+			costFactor = append(costFactor, f)
+			capacity = append(capacity, ceiling[i]/f)
+			weight = append(weight, p.trafficWeight(now, d.slowStart))
 		}
 	}
 
-	// create PODs
-	needNewPods := d.desiredReplicas - len(newPodList)
-	if needNewPods > 0 {
-		for range needNewPods {
-			newPodList = append(newPodList, pod{
-				status:           podStatusStarting,
-				lastStatusChange: time.Now(),
-			})
-			//fmt.Println("started")
+	assigned := b.distribute(offered, capacity, weight)
+
+	var served float64
+	for n, i := range serving {
+		p := &d.podList[i]
+		cpu := min(assigned[n], capacity[n]) * costFactor[n]
+		p.cpuUsage, p.throttled, p.throttleDelay = cfsThrottle(cpu, p.cpuLimit, d.burstiness)
+		p.load = p.cpuUsage / costFactor[n]
+		served += p.load
+	}
+
+	for i, p := range d.podList {
+		switch {
+		case p.cpuUsage < ceiling[i]*saturationThreshold:
+			d.podList[i].saturatedSince = time.Time{}
+		case p.saturatedSince.IsZero():
+			d.podList[i].saturatedSince = now
 		}
 	}
 
-	d.podList = newPodList
+	return served
+}
+
+// isBooting tells whether the pod container is starting up,
+// as opposed to waiting to recover from a failed readiness probe.
+func (d *deployment) isBooting(p pod) bool {
+	return p.status == podStatusNotReady && !p.readinessFailed
+}
+
+// reportsMetrics tells whether the pod CPU usage is reported to the HPA.
+// Ready pods always report it. Pods running but not ready yet report it
+// only while they burn CPU booting, otherwise the HPA sees them as missing
+// metrics, just like pods whose container is not running yet.
+func (d *deployment) reportsMetrics(p pod) bool {
+	switch {
+	case p.status == podStatusReady:
+		return true
+	case d.isBooting(p):
+		return d.startupCPU > 0
+	}
+	return false
+}
 
-	//d.log("after")
+// isUnready tells whether the pod is expected to become ready,
+// but is not ready now.
+func (p pod) isUnready() bool {
+	switch p.status {
+	case podStatusPending, podStatusContainerCreating, podStatusNotReady, podStatusCrashLoopBackOff:
+		return true
+	}
+	return false
+}
+
+// cpuUsageStats returns the min, average and max CPU usage among pods reporting metrics.
+func (d *deployment) cpuUsageStats() (lo, avg, hi float64) {
+	var pods int
+	lo = math.MaxFloat64
+	for _, p := range d.podList {
+		if !d.reportsMetrics(p) {
+			continue
+		}
+		pods++
+		avg += p.cpuUsage
+		lo = min(lo, p.cpuUsage)
+		hi = max(hi, p.cpuUsage)
+	}
+	if pods == 0 {
+		return 0, 0, 0
+	}
+	return lo, avg / float64(pods), hi
+}
+
+// metrics reports pod CPU usage as the metrics server would to the HPA.
+func (d *deployment) metrics() podMetrics {
+	var m podMetrics
+	for _, p := range d.podList {
+		switch {
+		case d.reportsMetrics(p):
+			m.pods++
+			m.cpuUsage += p.cpuUsage
+			m.cpuRequest += p.cpuRequest
+		case p.isUnready():
+			m.missingPods++
+			m.missingCPURequest += p.cpuRequest
+		}
+	}
+	return m
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+// maxEvents limits how many events are kept in the event log.
+const maxEvents = 50
+
+// event is a cluster event, like the ones listed by kubectl get events.
+type event struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	kind      string // Normal or Warning
+	reason    string // e.g. FailedCreate
+	object    string // e.g. replicaset/workload-1
+	message   string
+	count     int // occurrences, repeated events are aggregated
+}
+
+// eventLog keeps the latest events, oldest first.
+type eventLog struct {
+	events []event
+}
+
+// emit records an event. An event repeating the latest event
+// for the same object and reason is aggregated into it.
+func (l *eventLog) emit(now time.Time, kind, reason, object, message string) {
+	for i := len(l.events) - 1; i >= 0; i-- {
+		e := &l.events[i]
+		if e.object != object || e.reason != reason {
+			continue
+		}
+		if e.message == message && e.kind == kind {
+			e.count++
+			e.lastSeen = now
+			return
+		}
+		break
+	}
+	l.events = append(l.events, event{
+		firstSeen: now,
+		lastSeen:  now,
+		kind:      kind,
+		reason:    reason,
+		object:    object,
+		message:   message,
+		count:     1,
+	})
+	if extra := len(l.events) - maxEvents; extra > 0 {
+		l.events = l.events[extra:]
+	}
+	fmt.Printf("event: %s %s %s: %s\n", kind, reason, object, message)
+}
+
+// updateEventsLog writes the latest events, newest first, into the element.
+func updateEventsLog(el js.Value, l *eventLog, now time.Time, limit int) {
+	if len(l.events) == 0 {
+		el.Set("innerText", "No events.")
+		return
+	}
+	var sb strings.Builder
+	for i := len(l.events) - 1; i >= 0 && i >= len(l.events)-limit; i-- {
+		e := l.events[i]
+		age := now.Sub(e.lastSeen).Round(time.Second)
+		if e.count > 1 {
+			fmt.Fprintf(&sb, "%v ago (x%d over %v)\t%s\t%s\t%s\t%s\n", age, e.count,
+				e.lastSeen.Sub(e.firstSeen).Round(time.Second), e.kind, e.reason, e.object, e.message)
+			continue
+		}
+		fmt.Fprintf(&sb, "%v ago\t%s\t%s\t%s\t%s\n", age, e.kind, e.reason, e.object, e.message)
+	}
+	el.Set("innerText", sb.String())
+}
@@ -5,52 +5,99 @@ import (
 	"math"
 )
 
-// runHPADemoSimulation runs a simulation of HPA behavior based on the provided controls.
+// podMetrics is what the metrics server reports to the HPA.
+type podMetrics struct {
+	pods              int     // pods reporting CPU usage
+	missingPods       int     // pods not reporting CPU usage yet (e.g. Pending), see runHPADemoSimulation
+	cpuUsage          float64 // total CPU usage (mCores) of the pods reporting it
+	cpuRequest        float64 // total CPU request (mCores) of the pods reporting usage
+	missingCPURequest float64 // total CPU request (mCores) of the pods missing metrics
+}
+
+// hpaSpec is the HPA configuration of a workload, along with its current replicas.
+type hpaSpec struct {
+	currentPods          int
+	targetCPUUtilization int // percent of the CPU request
+	minReplicas          int
+	maxReplicas          int
+}
+
+// runHPADemoSimulation runs a simulation of HPA behavior based on the provided spec
+// and on the CPU usage measured for the pods.
 // HPA formula is:
-// DesiredPods = CurrentPods * (cpuMetric / TargetCPUUtilization)
+// DesiredPods = MetricPods * (cpuMetric / TargetCPUUtilization)
 // where cpuMetric = TotalCPUUsage / TotalCPURequest
-// and TotalCPURequest = PODCPURequest * CurrentPods
-// hence:
-// DesiredPods = TotalCPUUsage / (PODCPURequest * CurrentPods * TargetCPUUtilization)
+// and TotalCPURequest is the sum of the CPU requests of the MetricPods,
+// the pods reporting CPU usage. Every pod counts with its own request,
+// which differs from the template while an in-place resize is pending.
+// With equal requests:
+// DesiredPods = TotalCPUUsage / (PODCPURequest * TargetCPUUtilization)
 // DesiredPods is ceiled to the next integer if not an integer.
 // The result is then clamped between MinPods and MaxPods.
 //
+// Like the real controller, when scaling up, pods not reporting metrics yet
+// are assumed to use 0% of their request, in order to dampen the scale up
+// while new pods are still starting (or stuck Pending for lack of node capacity).
+// When scaling down, they are assumed to use 100% of their request
+// (or the target, if higher), in order to dampen the scale down.
+//
 // allowScale reports if scale tolerance allowed scaling.
-func runHPADemoSimulation(controls podControls) (desiredPodsInt int, allowScale bool) {
-	currentPods := getSliderValueAsInt(controls.sliderNumberOfPods.slider)
-	totalCPUUsage := getSliderValueAsInt(controls.sliderCPUUsage.slider)
-	podCPULimit := getSliderValueAsInt(controls.sliderPODCPULimit.slider)
-	podCPURequest := getSliderValueAsInt(controls.sliderPODCPURequest.slider)
-	targetCPUUtilization := getSliderValueAsInt(controls.sliderHPATargetCPUUtilization.slider)
-	minReplicas := getSliderValueAsInt(controls.sliderHPAMinReplicas.slider)
-	maxReplicas := getSliderValueAsInt(controls.sliderHPAMaxReplicas.slider)
-
-	// calculate totalCPULimit
-	totalCPULimit := podCPULimit * currentPods
-
-	// cannot actually load CPU more than the total CPU limit
-	if totalCPUUsage > totalCPULimit {
-		totalCPUUsage = totalCPULimit
-	}
+func runHPADemoSimulation(spec hpaSpec, metrics podMetrics) (desiredPodsInt int, allowScale bool) {
+	currentPods := spec.currentPods
+	targetCPUUtilization := spec.targetCPUUtilization
+	minReplicas := spec.minReplicas
+	maxReplicas := spec.maxReplicas
 
-	// calculate totalCPURequest
-	totalCPURequest := podCPURequest * currentPods
+	totalCPUUsage := metrics.cpuUsage
+	totalCPURequest := metrics.cpuRequest
 
 	// calculate cpuMetric
-	cpuMetric := float64(totalCPUUsage) / float64(totalCPURequest)
+	cpuMetric := totalCPUUsage / totalCPURequest
 
 	target := float64(targetCPUUtilization) / 100
 
 	// calculate currentMetric / desiredMetric
 	usageRatio := cpuMetric / target
 
-	// do not scale if within tolerance (cpuMetric close enough to target).
-	if withinTolerance(usageRatio) {
+	pods := metrics.pods
+
+	if usageRatio > 1 && metrics.missingPods > 0 {
+		// scaling up: assume pods missing metrics use 0% of their request
+		pods += metrics.missingPods
+		cpuMetric = totalCPUUsage / (totalCPURequest + metrics.missingCPURequest)
+		newUsageRatio := cpuMetric / target
+		if newUsageRatio < 1 {
+			// missing pods would reverse the scale direction
+			newUsageRatio = 1
+		}
+		usageRatio = newUsageRatio
+	} else if usageRatio < 1 && metrics.missingPods > 0 {
+		// scaling down: assume pods missing metrics use 100% of their request
+		fallback := max(1, target) * metrics.missingCPURequest
+		pods += metrics.missingPods
+		cpuMetric = (totalCPUUsage + fallback) / (totalCPURequest + metrics.missingCPURequest)
+		newUsageRatio := cpuMetric / target
+		if newUsageRatio > 1 {
+			// missing pods would reverse the scale direction
+			newUsageRatio = 1
+		}
+		usageRatio = newUsageRatio
+	}
+
+	switch {
+	case metrics.pods == 0:
+		fmt.Printf("hpademo %s: no pods reporting metrics, not scaling\n", version)
+		desiredPodsInt = currentPods
+	case totalCPURequest <= 0:
+		fmt.Printf("hpademo %s: missing request for cpu, cannot compute utilization, not scaling\n", version)
+		desiredPodsInt = currentPods
+	case withinTolerance(usageRatio):
+		// do not scale if within tolerance (cpuMetric close enough to target).
 		fmt.Printf("hpademo %s: within tolerance: cpuMetric=%v target=%v usageRatio=%v tolerance=%v ratioRange=(%v - %v), not scaling\n", version, cpuMetric, target, usageRatio, scaleTolerance, (1.0 - scaleTolerance), (1.0 + scaleTolerance))
 		desiredPodsInt = currentPods
-	} else {
+	default:
 		// calculate DesiredPods
-		desiredPods := float64(currentPods) * usageRatio
+		desiredPods := float64(pods) * usageRatio
 
 		// ceil DesiredPods to next integer using math.Ceil function
 		desiredPodsInt = int(math.Ceil(desiredPods))
@@ -77,8 +124,8 @@ func runHPADemoSimulation(controls podControls) (desiredPodsInt int, allowScale
 		fmt.Printf("WARN: HPA Min Replicas (%d) is greater than HPA Max Replicas (%d)\n", minReplicas, maxReplicas)
 	}
 
-	fmt.Printf("hpademo %s: currentPods=%d totalCPUUsage=%d podCPURequest=%d cpuMetric=%v targetCPUUtilization=%v => desiredPods=%d\n",
-		version, currentPods, totalCPUUsage, podCPURequest, cpuMetric, target, desiredPodsInt)
+	fmt.Printf("hpademo %s: currentPods=%d metricPods=%d missingPods=%d totalCPUUsage=%v totalCPURequest=%v cpuMetric=%v targetCPUUtilization=%v => desiredPods=%d\n",
+		version, currentPods, metrics.pods, metrics.missingPods, totalCPUUsage, totalCPURequest, cpuMetric, target, desiredPodsInt)
 
 	return desiredPodsInt, allowScale
 }
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// karpenterBatchInterval is how long karpenter batches pending pods
+// before provisioning, and how often it looks for consolidation.
+const karpenterBatchInterval = 10 * time.Second
+
+// instanceType is an entry of the karpenter instance catalog.
+type instanceType struct {
+	name   string
+	cpu    float64 // allocatable mCores
+	memory float64 // allocatable MiB
+	price  float64 // per hour
+}
+
+// parseCatalog parses a comma-separated list of instance types
+// in the format name:cpu:memory:price, e.g. "small:2000:4096:0.1".
+func parseCatalog(s string) ([]instanceType, error) {
+	var catalog []instanceType
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fields := strings.Split(entry, ":")
+		if len(fields) != 4 || fields[0] == "" {
+			return nil, fmt.Errorf("instance type %q: want name:cpu:memory:price", entry)
+		}
+		var values [3]float64
+		for i, f := range fields[1:] {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("instance type %q: bad value %q", entry, f)
+			}
+			values[i] = v
+		}
+		catalog = append(catalog, instanceType{
+			name:   fields[0],
+			cpu:    values[0],
+			memory: values[1],
+			price:  values[2],
+		})
+	}
+	return catalog, nil
+}
+
+type consolidationPolicy string
+
+const (
+	consolidateWhenEmpty                consolidationPolicy = "WhenEmpty"                // remove empty nodes only
+	consolidateWhenEmptyOrUnderutilized consolidationPolicy = "WhenEmptyOrUnderutilized" // also remove or replace underutilized nodes
+)
+
+// karpenter provisions right-sized instances for pending pods, picked from
+// the instance catalog, and consolidates the instances it has provisioned,
+// removing them or replacing them with cheaper ones, like Karpenter.
+// Node group nodes are left alone.
+//
+// See: https://karpenter.sh/docs/concepts/disruption/
+type karpenter struct {
+	enabled           bool
+	catalog           []instanceType
+	catalogText       string // catalog source, see setCatalog
+	maxNodes          int
+	provisioningDelay time.Duration // time for a new instance to become ready
+	policy            consolidationPolicy
+	consolidateAfter  time.Duration // time a node must be consolidatable before acting
+	lastScan          time.Time
+}
+
+// setCatalog parses the catalog text when it changes.
+// A bad catalog is reported and the previous catalog is kept.
+func (k *karpenter) setCatalog(text string) {
+	if text == k.catalogText {
+		return
+	}
+	k.catalogText = text
+	catalog, err := parseCatalog(text)
+	if err != nil {
+		fmt.Printf("Error parsing instance catalog: %v\n", err)
+		return
+	}
+	k.catalog = catalog
+}
+
+// run provisions instances for unschedulable pods every karpenterBatchInterval.
+// When there is nothing to provision, it consolidates.
+func (k *karpenter) run(c *cluster, now time.Time) {
+	if !k.enabled || now.Sub(k.lastScan) < karpenterBatchInterval {
+		return
+	}
+	k.lastScan = now
+
+	c.reserve()
+
+	if k.provision(c, now) {
+		return
+	}
+	k.finishReplacements(c, now)
+	k.consolidate(c, now)
+}
+
+// provision launches the cheapest instances that fit the unschedulable pods,
+// taking into account the room on nodes still provisioning.
+// It returns true if it launched instances.
+func (k *karpenter) provision(c *cluster, now time.Time) bool {
+	var upcoming []node
+	for _, n := range c.nodes {
+		if n.status(now) == nodeStatusProvisioning {
+			upcoming = append(upcoming, n)
+		}
+	}
+
+	var pending []pod
+	for _, p := range c.pods() {
+		if p.unschedulable && !p.zoneBlocked && !placeFirstFit(upcoming, *p) {
+			pending = append(pending, *p)
+		}
+	}
+
+	var launched int
+	for len(pending) > 0 && len(c.nodes) < k.maxNodes {
+		it, rest, ok := k.cheapestFit(pending)
+		if !ok {
+			break // no instance type fits the remaining pods
+		}
+		c.addInstance(it, now.Add(k.provisioningDelay))
+		pending = rest
+		launched++
+	}
+
+	return launched > 0
+}
+
+// cheapestFit picks the instance type with the lowest price per pod packed,
+// packing the pods first-fit into one empty instance.
+// It returns the pods left out, and false if no instance type fits any pod.
+func (k *karpenter) cheapestFit(pods []pod) (best instanceType, rest []pod, ok bool) {
+	bestCost := math.MaxFloat64
+	for _, it := range k.catalog {
+		left := pack(it, pods)
+		packed := len(pods) - len(left)
+		if packed == 0 {
+			continue
+		}
+		if cost := it.price / float64(packed); cost < bestCost {
+			bestCost = cost
+			best, rest, ok = it, left, true
+		}
+	}
+	return best, rest, ok
+}
+
+// cheapestFitAll picks the cheapest instance type fitting all the pods.
+// It returns false if no instance type fits them all.
+func (k *karpenter) cheapestFitAll(pods []pod) (best instanceType, ok bool) {
+	for _, it := range k.catalog {
+		if len(pack(it, pods)) == 0 && (!ok || it.price < best.price) {
+			best, ok = it, true
+		}
+	}
+	return best, ok
+}
+
+// pack places the pods first-fit into one empty instance of the given type.
+// It returns the pods that do not fit.
+func pack(it instanceType, pods []pod) []pod {
+	n := node{cpu: it.cpu, memory: it.memory}
+	var left []pod
+	for _, p := range pods {
+		if !n.fits(p.cpuRequest, p.memoryRequest) {
+			left = append(left, p)
+			continue
+		}
+		n.cpuRequested += p.cpuRequest
+		n.memoryRequested += p.memoryRequest
+	}
+	return left
+}
+
+// addInstance launches an instance which becomes ready at readyAt.
+func (c *cluster) addInstance(it instanceType, readyAt time.Time) int {
+	c.lastNodeID++
+	c.nodes = append(c.nodes, node{
+		id:           c.lastNodeID,
+		cpu:          it.cpu,
+		memory:       it.memory,
+		readyAt:      readyAt,
+		instanceType: it.name,
+		price:        it.price,
+		zone:         c.pickZone(),
+	})
+	return c.lastNodeID
+}
+
+// finishReplacements drains the nodes whose replacement is ready.
+func (k *karpenter) finishReplacements(c *cluster, now time.Time) {
+	for i, n := range c.nodes {
+		if n.replacement == 0 {
+			continue
+		}
+		r := c.findNode(n.replacement)
+		switch {
+		case r == nil:
+			c.nodes[i].replacement = 0 // replacement is gone, start over
+		case r.status(now) == nodeStatusReady:
+			c.nodes[i].replacement = 0
+			c.drain(&c.nodes[i], now)
+		}
+	}
+}
+
+// consolidationAction is what consolidation does to a node.
+type consolidationAction int
+
+const (
+	consolidateNone    consolidationAction = iota
+	consolidateDelete                      // pods fit on other nodes
+	consolidateReplace                     // pods fit on a cheaper instance
+)
+
+// consolidate acts on the first ready instance found consolidatable
+// for consolidateAfter, one node at a time: it waits for the instance
+// it is draining or replacing, ignoring the nodes of the node group
+// and the nodes down for other reasons.
+func (k *karpenter) consolidate(c *cluster, now time.Time) {
+	for _, n := range c.nodes {
+		if n.instanceType != "" && (n.draining || n.replacement != 0) {
+			return // wait for the previous disruption to settle
+		}
+	}
+
+	// least utilized nodes first
+	candidates := slices.Clone(c.nodes)
+	slices.SortFunc(candidates, func(a, b node) int {
+		return cmp.Compare(a.utilization(), b.utilization())
+	})
+
+	acted := false
+	for _, cand := range candidates {
+		n := c.findNode(cand.id)
+		action, it := k.consolidation(c, *n, now)
+		if action == consolidateNone {
+			n.unneededSince = time.Time{}
+			continue
+		}
+		if n.unneededSince.IsZero() {
+			n.unneededSince = now
+		}
+		if acted || now.Sub(n.unneededSince) < k.consolidateAfter {
+			continue
+		}
+		acted = true
+		if action == consolidateReplace {
+			id := c.addInstance(it, now.Add(k.provisioningDelay)) // invalidates n
+			c.findNode(cand.id).replacement = id
+			continue
+		}
+		c.drain(n, now)
+	}
+}
+
+// consolidation returns what consolidation would do to the node,
+// and the cheaper instance type to replace it with, if any.
+func (k *karpenter) consolidation(c *cluster, n node, now time.Time) (consolidationAction, instanceType) {
+	if n.instanceType == "" {
+		return consolidateNone, instanceType{} // not provisioned by karpenter
+	}
+	if n.status(now) != nodeStatusReady {
+		return consolidateNone, instanceType{}
+	}
+	if n.cpuRequested == 0 && n.memoryRequested == 0 {
+		return consolidateDelete, instanceType{}
+	}
+	if k.policy != consolidateWhenEmptyOrUnderutilized {
+		return consolidateNone, instanceType{}
+	}
+	if c.podsFitElsewhere(n, now) {
+		return consolidateDelete, instanceType{}
+	}
+
+	var nodePods []pod
+	for _, p := range c.pods() {
+		if p.node == n.id && p.holdsResources() {
+			nodePods = append(nodePods, *p)
+		}
+	}
+	it, ok := k.cheapestFitAll(nodePods)
+	if ok && it.price < n.price {
+		return consolidateReplace, it
+	}
+
+	return consolidateNone, instanceType{}
+}
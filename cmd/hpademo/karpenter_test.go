@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCatalog(t *testing.T) {
+	catalog, err := parseCatalog(" small:2000:4096:0.1 ,, large:8000:32768:0.4, ")
+	if err != nil {
+		t.Fatalf("valid catalog: %v", err)
+	}
+	want := []instanceType{
+		{name: "small", cpu: 2000, memory: 4096, price: 0.1},
+		{name: "large", cpu: 8000, memory: 32768, price: 0.4},
+	}
+	if len(catalog) != len(want) || catalog[0] != want[0] || catalog[1] != want[1] {
+		t.Errorf("catalog %+v, want %+v, blank entries skipped", catalog, want)
+	}
+
+	if catalog, err := parseCatalog(" , "); err != nil || len(catalog) != 0 {
+		t.Errorf("blank catalog: %+v, %v, want empty without error", catalog, err)
+	}
+}
+
+func TestParseCatalogRejects(t *testing.T) {
+	for _, bad := range []string{
+		"small:2000:4096",          // missing price
+		"small:2000:4096:0.1:x",    // extra field
+		":2000:4096:0.1",           // no name
+		"small:2k:4096:0.1",        // not a number
+		"small:0:4096:0.1",         // no CPU
+		"small:2000:-1:0.1",        // negative memory
+		"small:2000:4096:0",        // free instance
+		"ok:1:1:1,small:2000:4096", // one bad entry spoils the catalog
+	} {
+		catalog, err := parseCatalog(bad)
+		if err == nil {
+			t.Errorf("%q: parsed as %+v, want an error", bad, catalog)
+			continue
+		}
+		if !strings.Contains(err.Error(), "instance type") {
+			t.Errorf("%q: error %q does not name the instance type", bad, err)
+		}
+	}
+}
@@ -4,27 +4,12 @@ package main
 import (
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"strconv"
 	"syscall/js"
 	"time"
 )
 
-type subchart struct {
-	ctx    js.Value
-	legend js.Value
-	data   []int
-}
-
-type chart struct {
-	pods         subchart
-	podsStarting subchart
-	podsStopping subchart
-	podsLoad     subchart
-	unmetLoad    subchart
-	canvasWidth  int
-	canvasHeight int
-}
-
 func getSliderValueAsInt(slider js.Value) int {
 	s := slider.Get("value").String()
 	i, err := strconv.Atoi(s)
@@ -43,32 +28,50 @@ func main() {
 	titleElement := document.Call("getElementById", "title")
 	titleElement.Set("innerHTML", titleVersion)
 
-	// find canvasPods element
-	canvasPods := document.Call("getElementById", "canvas_pods")
-	canvasPodsLegend := document.Call("getElementById", "canvas_pods_legend")
-	canvasPodsCtx := canvasPods.Call("getContext", "2d")
+	errorRateSLI := document.Call("getElementById", "error_rate_sli")
+	overprovisioningStats := document.Call("getElementById", "overprovisioning_stats")
+	workloadStats := document.Call("getElementById", "workload_stats")
+	quotaStats := document.Call("getElementById", "quota_stats")
+	rolloutStats := document.Call("getElementById", "rollout_stats")
+	disruptionStats := document.Call("getElementById", "disruption_stats")
+	chaosStats := document.Call("getElementById", "chaos_stats")
+	zoneStats := document.Call("getElementById", "zone_stats")
+	vpaStats := document.Call("getElementById", "vpa_stats")
+	throttleStats := document.Call("getElementById", "throttle_stats")
+	eventsLog := document.Call("getElementById", "events_log")
+	podList := document.Call("getElementById", "pod_list")
+
+	canvasTimeline := document.Call("getElementById", "canvas_pod_timeline")
+	canvasTimelineLegend := document.Call("getElementById", "canvas_pod_timeline_legend")
+	canvasTimelineCtx := canvasTimeline.Call("getContext", "2d")
+	timelineWidth := canvasTimeline.Get("width").Int()
+	timelineHeight := canvasTimeline.Get("height").Int()
+
+	// seeded random number generator shared by the simulation
+	seed := rand.NewPCG(1, 1)
+	rng := rand.New(seed)
+
+	cl := &cluster{}
 
-	canvasPodsLoad := document.Call("getElementById", "canvas_pod_cpu_usage")
-	canvasPodsLoadLegend := document.Call("getElementById", "canvas_pod_cpu_usage_legend")
-	canvasPodsLoadCtx := canvasPodsLoad.Call("getContext", "2d")
-
-	canvasUnmetLoad := document.Call("getElementById", "canvas_unmet_cpu_load")
-	canvasUnmetLoadLegend := document.Call("getElementById", "canvas_unmet_cpu_load_legend")
-	canvasUnmetLoadCtx := canvasUnmetLoad.Call("getContext", "2d")
+	const historySize = 600
 
-	deploy := deployment{
-		desiredReplicas: 1,
+	var workloads []*workload
+	for i := range maxWorkloads {
+		workloads = append(workloads, newWorkload(document, fmt.Sprintf("workload-%d", i+1), cl, rng, historySize))
+		cl.workloads = append(cl.workloads, workloads[i].deploy)
 	}
+	selected := workloads[0]
 
-	// get canvas width and height
-	canvasWidth := canvasPods.Get("width").Int()
-	canvasHeight := canvasPods.Get("height").Int()
+	placeholders := newPlaceholders(cl, rng)
 
-	const historySize = 600
+	cl.workloads = append(cl.workloads, placeholders)
+
+	var autoscaler clusterAutoscaler
+	var provisioner karpenter
+	var maint maintenance
+	monkey := chaos{rng: rng}
 
-	c := newChart(canvasPodsCtx, canvasPodsLoadCtx, canvasUnmetLoadCtx,
-		canvasPodsLegend, canvasPodsLoadLegend, canvasUnmetLoadLegend,
-		canvasWidth, canvasHeight, historySize)
+	timelineWindow := historySize * time.Second
 
 	controls := addHTMLControls(document, func(value string) {
 		// Update history size based on slider input
@@ -77,108 +80,195 @@ func main() {
 			fmt.Printf("Error converting history size to int: %v\n", err)
 			return
 		}
-		c.resizeHistory(historySize)
+		for _, w := range workloads {
+			w.chart.resizeHistory(historySize)
+			w.sli.resize(historySize)
+		}
+		timelineWindow = time.Duration(historySize) * time.Second
+	}, func(value string) {
+		// Reseed random number generator based on seed input
+		s, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			fmt.Printf("Error converting random seed to int: %v\n", err)
+			return
+		}
+		seed.Seed(s, s)
 	})
 
-	// call function to draw chart
-	drawCharts(canvasPodsCtx, canvasPodsLoadCtx, canvasUnmetLoadCtx, c)
+	// every workload starts from the default settings
+	for _, w := range workloads {
+		w.save(controls)
+	}
+
+	controls.selectWorkload.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) any {
+		// Show the settings and charts of the selected workload
+		i, err := strconv.Atoi(controls.selectWorkload.Get("value").String())
+		if err != nil || i < 1 || i > len(workloads) {
+			fmt.Printf("Error selecting workload: %v\n", err)
+			return nil
+		}
+		selected.save(controls)
+		selected = workloads[i-1]
+		selected.load(controls)
+		drawCharts(selected.chart)
+		return nil
+	}))
+
+	controls.buttonDeployNewVersion.Call("addEventListener", "click", js.FuncOf(func(this js.Value, args []js.Value) any {
+		// Roll out a new pod template on the selected workload
+		selected.deploy.deployNewVersion(time.Now())
+		return nil
+	}))
+
+	controls.buttonDrainNode.Call("addEventListener", "click", js.FuncOf(func(this js.Value, args []js.Value) any {
+		// Drain the first ready node for maintenance
+		maint.drainNode(cl, time.Now())
+		return nil
+	}))
+
+	controls.buttonClusterUpgrade.Call("addEventListener", "click", js.FuncOf(func(this js.Value, args []js.Value) any {
+		// Drain and upgrade every node, one at a time
+		maint.upgradeCluster(cl, time.Now())
+		return nil
+	}))
+
+	controls.buttonKillPods.Call("addEventListener", "click", js.FuncOf(func(this js.Value, args []js.Value) any {
+		// Kill pods of the selected workload at once
+		monkey.killPods(cl, selected.deploy, getSliderValueAsInt(controls.sliderKillPods.slider), time.Now())
+		return nil
+	}))
+
+	controls.buttonSpotInterruption.Call("addEventListener", "click", js.FuncOf(func(this js.Value, args []js.Value) any {
+		// Interrupt a fraction of the nodes as spot instances
+		monkey.interruptSpot(cl, time.Now())
+		return nil
+	}))
 
-	var lastHPAEvaluation int
-	var lastScanlingDown time.Time
+	controls.buttonZoneOutage.Call("addEventListener", "click", js.FuncOf(func(this js.Value, args []js.Value) any {
+		// Take the zone down, losing every pod in it
+		zone, _ := strconv.Atoi(controls.selectOutageZone.Get("value").String())
+		duration := time.Second * time.Duration(getSliderValueAsInt(controls.sliderOutageDuration.slider))
+		cl.zoneOutage(zone, duration, time.Now())
+		return nil
+	}))
+
+	// call function to draw chart
+	drawCharts(selected.chart)
 
 	// call updateChart every second
 	js.Global().Call("setInterval", js.FuncOf(func(this js.Value, args []js.Value) any {
+		selected.save(controls)
+
 		//
-		// evaluate hpa
+		// evaluate hpa of every workload
 		//
-		var newPodValue int
-		lastHPAEvaluation++
-		if lastHPAEvaluation >= 15 {
-			// get from HPA simulation
-			lastHPAEvaluation = 0
-			oldPodValue := getSliderValueAsInt(controls.sliderNumberOfPods.slider)
-
-			var isScaleToleranceAllowed bool
-			newPodValue, isScaleToleranceAllowed = runHPADemoSimulation(controls)
-
-			isScaling := newPodValue != oldPodValue
-
-			var isScalingDownAllowed bool
-
-			isScalingDown := newPodValue < oldPodValue
-			if isScalingDown {
-				// scaling down, check stabilization window
-				elapSecs := time.Since(lastScanlingDown).Seconds()
-				scaleDownWindow := float64(getSliderValueAsInt(controls.sliderScaleDownStabilizationWindow.slider))
-				isScalingDownAllowed = elapSecs > scaleDownWindow
-				if isScalingDownAllowed {
-					lastScanlingDown = time.Now()
-				} else {
-					fmt.Printf("lastScaleDown=%v <= scaleDownStabilizationWindow=%v, not scaling down\n",
-						elapSecs, scaleDownWindow)
-				}
-			}
 
-			// isScaleToleranceAllowed
-			// isScalingDownAllowed
-			// isScaling
-			// isScalingDown
-			willScale := true
-			if !isScaleToleranceAllowed {
-				willScale = false // do not scale because ratio is within Tolerance range
-			}
-			if !isScaling {
-				willScale = false // do not scale because pods unchanged
-			}
-			if isScalingDown && !isScalingDownAllowed {
-				// do not scale because cannot scale down within stabilization window
-				willScale = false
-			}
+		split := trafficSplit{
+			enabled: controls.selectTrafficSplit.Get("value").String() == "canary",
+			weight:  float64(getSliderValueAsInt(controls.sliderCanaryWeight.slider)) / 100,
+		}
 
-			if willScale {
-				// update number of pods slider to reflect HPA decision
-				controls.sliderNumberOfPods.slider.Set("value", newPodValue)
-				controls.sliderNumberOfPods.textBox.Set("value", newPodValue)
-			} else {
-				newPodValue = oldPodValue // revert scale
+		activeWorkloads := split.activeWorkloads(getSliderValueAsInt(controls.sliderWorkloads.slider))
+		for i, w := range workloads {
+			w.active = i < activeWorkloads
+			var replicas int
+			if w.active {
+				replicas = w.autoscale(controls, w == selected)
 			}
-		} else {
-			// get from slider
-			newPodValue = getSliderValueAsInt(controls.sliderNumberOfPods.slider)
+			w.configure(controls)
+			w.deploy.scale(replicas)
 		}
 
-		deploy.startupTime = time.Second * time.Duration(getSliderValueAsInt(controls.sliderPODStartupTime.slider))
-		deploy.stopTime = time.Second * time.Duration(getSliderValueAsInt(controls.sliderPODStopTime.slider))
-
-		deploy.scale(newPodValue)
+		nodeAutoscaler := controls.selectNodeAutoscaler.Get("value").String()
+
+		autoscaler.enabled = nodeAutoscaler == "cluster-autoscaler"
+		autoscaler.minNodes = getSliderValueAsInt(controls.sliderNodeCount.slider)
+		autoscaler.maxNodes = getSliderValueAsInt(controls.sliderCAMaxNodes.slider)
+		autoscaler.provisioningDelay = time.Second * time.Duration(getSliderValueAsInt(controls.sliderCAProvisioningDelay.slider))
+		autoscaler.unneededTime = time.Second * time.Duration(getSliderValueAsInt(controls.sliderCAUnneededTime.slider))
+		autoscaler.utilizationThreshold = float64(getSliderValueAsInt(controls.sliderCAUtilizationThreshold.slider)) / 100
+
+		cl.setZones(getSliderValueAsInt(controls.sliderZones.slider))
+		cl.resize(autoscaler.minNodes,
+			float64(getSliderValueAsInt(controls.sliderNodeCPU.slider)),
+			float64(getSliderValueAsInt(controls.sliderNodeMemory.slider)),
+			autoscaler.enabled, time.Now())
+		cl.runOutages(time.Now())
+
+		provisioner.enabled = nodeAutoscaler == "karpenter"
+		provisioner.maxNodes = autoscaler.maxNodes
+		provisioner.provisioningDelay = autoscaler.provisioningDelay
+		provisioner.policy = consolidationPolicy(controls.selectConsolidationPolicy.Get("value").String())
+		provisioner.consolidateAfter = time.Second * time.Duration(getSliderValueAsInt(controls.sliderConsolidateAfter.slider))
+		provisioner.setCatalog(controls.textInstanceCatalog.Get("value").String())
+
+		// higher priority pending pods are scheduled first
+		for _, w := range byPriority(workloads) {
+			w.deploy.update()
+		}
 
-		deploy.update()
+		placeholders.schedulingTime = workloads[0].deploy.schedulingTime
+		placeholders.cpuRequest = float64(getSliderValueAsInt(controls.sliderPlaceholderCPURequest.slider))
+		placeholders.memoryRequest = float64(getSliderValueAsInt(controls.sliderPlaceholderMemoryRequest.slider))
+		placeholders.scale(getSliderValueAsInt(controls.sliderPlaceholderPods.slider))
+		placeholders.update()
 
-		//
-		// evaluate per pod load
-		//
+		cl.removeDrained(time.Now())
+		autoscaler.run(cl, time.Now())
+		provisioner.run(cl, time.Now())
 
-		currentPods := float64(deploy.getReplicas())
-		totalCPUUsage := float64(getSliderValueAsInt(controls.sliderCPUUsage.slider))
-		podCPULimit := float64(getSliderValueAsInt(controls.sliderPODCPULimit.slider))
+		maint.downTime = time.Second * time.Duration(getSliderValueAsInt(controls.sliderMaintenanceTime.slider))
+		maint.run(cl, time.Now())
 
-		newPodLoad := totalCPUUsage / currentPods
-		newPodLoad = min(newPodLoad, podCPULimit)
+		monkey.podKillRate = float64(getSliderValueAsInt(controls.sliderPodKillRate.slider))
+		monkey.spotRate = float64(getSliderValueAsInt(controls.sliderSpotRate.slider))
+		monkey.spotFraction = float64(getSliderValueAsInt(controls.sliderSpotFraction.slider)) / 100
+		monkey.spotNotice = time.Second * time.Duration(getSliderValueAsInt(controls.sliderSpotNotice.slider))
+		monkey.provisioningDelay = autoscaler.provisioningDelay
+		monkey.run(cl, cl.workloads[:len(workloads)], time.Now())
 
 		//
-		// evaluate total unmet load
+		// serve the load of every workload
 		//
 
-		metLoad := newPodLoad * currentPods
-		newUnmetLoad := totalCPUUsage - metLoad
+		clusterCPU, clusterMemory := cl.requestedPercent(time.Now())
+
+		sample := chartSample{
+			nodesByStatus: cl.countByStatus(time.Now()),
+			clusterCPU:    int(math.Round(clusterCPU)),
+			clusterMemory: int(math.Round(clusterMemory)),
+			evictions:     cl.evictions,
+		}
+		for i, w := range workloads {
+			sample.podsByWorkload[i] = w.deploy.getReplicas()
+		}
+
+		split.route(workloads[0], workloads[1])
 
-		// update chart data
-		updateChart(&c,
-			deploy.getReplicas(), deploy.getStarting(), deploy.getStopping(),
-			int(newPodLoad), int(newUnmetLoad))
+		for _, w := range workloads {
+			w.serve(controls, sample)
+		}
 
 		// redraw chart
-		drawCharts(canvasPodsCtx, canvasPodsLoadCtx, canvasUnmetLoadCtx, c)
+		drawCharts(selected.chart)
+		updateSLILegend(errorRateSLI, selected.sli.summary())
+		updateOverprovisioningLegend(overprovisioningStats, selected.deploy, placeholders)
+		updateWorkloadsLegend(workloadStats, workloads)
+		updateQuotaLegend(quotaStats, selected.deploy)
+		updateRolloutLegend(rolloutStats, selected.deploy, time.Now())
+		updateDisruptionLegend(disruptionStats, selected.deploy, &maint, cl)
+		updateChaosLegend(chaosStats, &monkey, cl)
+		updateZoneLegend(zoneStats, selected.deploy, selected.settings.int(controls.sliderHPAMinReplicas),
+			selected.demand*float64(selected.settings.int(controls.sliderCPUCost))/100)
+		updateVPALegend(vpaStats, selected.deploy, selected.settings.int(controls.sliderHPATargetCPUUtilization))
+		updateThrottlingLegend(throttleStats, selected.deploy)
+
+		now := time.Now()
+		timelinePods := selected.deploy.timelinePods(now, timelineWindow)
+		drawTimeline(canvasTimelineCtx, timelineWidth, timelineHeight, timelinePods, now, timelineWindow)
+		updateTimelineLegend(canvasTimelineLegend, timelinePods)
+		updateEventsLog(eventsLog, &cl.events, now, 20)
+		updatePodList(podList, selected.deploy, now)
 
 		return nil
 	}), 1000)
@@ -193,22 +283,167 @@ type podControls struct {
 	sliderCPUUsage                     sliderControl
 	sliderPODCPURequest                sliderControl
 	sliderPODCPULimit                  sliderControl
+	sliderResizeDelay                  sliderControl
+	sliderLoadBurstiness               sliderControl
+	selectQoSClass                     js.Value
 	sliderHPAMinReplicas               sliderControl
 	sliderHPAMaxReplicas               sliderControl
 	sliderHPATargetCPUUtilization      sliderControl
 	sliderNumberOfPods                 sliderControl
 	sliderHistorySize                  sliderControl
 	sliderScaleDownStabilizationWindow sliderControl
+	sliderPODSchedulingTime            sliderControl
+	sliderPODImagePullTime             sliderControl
 	sliderPODStartupTime               sliderControl
+	selectStartupDistribution          js.Value
+	sliderStartupSpread                sliderControl
+	sliderRandomSeed                   sliderControl
+	sliderPODPreStopTime               sliderControl
 	sliderPODStopTime                  sliderControl
+	sliderPODGracePeriod               sliderControl
+	sliderPODStartupCPU                sliderControl
+	selectSheddingPolicy               js.Value
+	sliderSheddingQueueSize            sliderControl
+	sliderSheddingQueueTimeout         sliderControl
+	selectBalancingStrategy            js.Value
+	sliderStickySkew                   sliderControl
+	sliderSlowStartWindow              sliderControl
+	sliderColdCPUPenalty               sliderControl
+	sliderColdWarmUpTime               sliderControl
+	sliderReadinessFailure             sliderControl
+	sliderLivenessFailure              sliderControl
+	sliderStartupFailure               sliderControl
+	sliderCrashRate                    sliderControl
+	sliderPODMemoryBaseline            sliderControl
+	sliderPODMemoryPerLoad             sliderControl
+	sliderPODMemoryLeakRate            sliderControl
+	sliderPODMemoryLimit               sliderControl
+	sliderPODMemoryRequest             sliderControl
+	sliderNodeCount                    sliderControl
+	sliderNodeCPU                      sliderControl
+	sliderNodeMemory                   sliderControl
+	selectNodeAutoscaler               js.Value
+	sliderCAMaxNodes                   sliderControl
+	sliderCAProvisioningDelay          sliderControl
+	sliderCAUnneededTime               sliderControl
+	sliderCAUtilizationThreshold       sliderControl
+	selectConsolidationPolicy          js.Value
+	sliderConsolidateAfter             sliderControl
+	textInstanceCatalog                js.Value
+	sliderPlaceholderPods              sliderControl
+	sliderPlaceholderCPURequest        sliderControl
+	sliderPlaceholderMemoryRequest     sliderControl
+	sliderWorkloads                    sliderControl
+	selectWorkload                     js.Value
+	sliderWorkloadPriority             sliderControl
+	selectPreemptionPolicy             js.Value
+	sliderQuotaPods                    sliderControl
+	sliderQuotaRequestsCPU             sliderControl
+	sliderQuotaLimitsCPU               sliderControl
+	sliderMaxSurge                     sliderControl
+	sliderMaxUnavailable               sliderControl
+	buttonDeployNewVersion             js.Value
+	sliderCPUCost                      sliderControl
+	selectTrafficSplit                 js.Value
+	sliderCanaryWeight                 sliderControl
+	selectPDBPolicy                    js.Value
+	sliderPDBValue                     sliderControl
+	sliderMaintenanceTime              sliderControl
+	buttonDrainNode                    js.Value
+	buttonClusterUpgrade               js.Value
+	sliderPodKillRate                  sliderControl
+	sliderKillPods                     sliderControl
+	buttonKillPods                     js.Value
+	sliderSpotRate                     sliderControl
+	sliderSpotFraction                 sliderControl
+	sliderSpotNotice                   sliderControl
+	buttonSpotInterruption             js.Value
+	selectWorkloadKind                 js.Value
+	selectPodManagementPolicy          js.Value
+	sliderZones                        sliderControl
+	selectOutageZone                   js.Value
+	sliderOutageDuration               sliderControl
+	buttonZoneOutage                   js.Value
+	selectSpreadPolicy                 js.Value
+	sliderMaxSkew                      sliderControl
+	selectVPAMode                      js.Value
+	selectVPAResources                 js.Value
+}
+
+// workloadSliders returns the slider controls holding workload settings,
+// see workloadSettings.
+func (controls podControls) workloadSliders() []sliderControl {
+	return []sliderControl{
+		controls.sliderCPUUsage,
+		controls.sliderPODCPURequest,
+		controls.sliderPODCPULimit,
+		controls.sliderResizeDelay,
+		controls.sliderLoadBurstiness,
+		controls.sliderHPAMinReplicas,
+		controls.sliderHPAMaxReplicas,
+		controls.sliderHPATargetCPUUtilization,
+		controls.sliderNumberOfPods,
+		controls.sliderScaleDownStabilizationWindow,
+		controls.sliderPODSchedulingTime,
+		controls.sliderPODImagePullTime,
+		controls.sliderPODStartupTime,
+		controls.sliderStartupSpread,
+		controls.sliderPODPreStopTime,
+		controls.sliderPODStopTime,
+		controls.sliderPODGracePeriod,
+		controls.sliderPODStartupCPU,
+		controls.sliderSheddingQueueSize,
+		controls.sliderSheddingQueueTimeout,
+		controls.sliderStickySkew,
+		controls.sliderSlowStartWindow,
+		controls.sliderColdCPUPenalty,
+		controls.sliderColdWarmUpTime,
+		controls.sliderReadinessFailure,
+		controls.sliderLivenessFailure,
+		controls.sliderStartupFailure,
+		controls.sliderCrashRate,
+		controls.sliderPODMemoryBaseline,
+		controls.sliderPODMemoryPerLoad,
+		controls.sliderPODMemoryLeakRate,
+		controls.sliderPODMemoryLimit,
+		controls.sliderPODMemoryRequest,
+		controls.sliderWorkloadPriority,
+		controls.sliderQuotaPods,
+		controls.sliderQuotaRequestsCPU,
+		controls.sliderQuotaLimitsCPU,
+		controls.sliderMaxSurge,
+		controls.sliderMaxUnavailable,
+		controls.sliderCPUCost,
+		controls.sliderPDBValue,
+		controls.sliderMaxSkew,
+	}
+}
+
+// workloadSelects returns the select controls holding workload settings,
+// see workloadSettings.
+func (controls podControls) workloadSelects() []js.Value {
+	return []js.Value{
+		controls.selectStartupDistribution,
+		controls.selectSheddingPolicy,
+		controls.selectBalancingStrategy,
+		controls.selectPreemptionPolicy,
+		controls.selectPDBPolicy,
+		controls.selectWorkloadKind,
+		controls.selectPodManagementPolicy,
+		controls.selectSpreadPolicy,
+		controls.selectVPAMode,
+		controls.selectVPAResources,
+		controls.selectQoSClass,
+	}
 }
 
 type sliderControl struct {
+	id      string // slider element id
 	slider  js.Value
 	textBox js.Value
 }
 
-func addHTMLControls(document js.Value, callbackHistorySize func(string)) podControls {
+func addHTMLControls(document js.Value, callbackHistorySize, callbackRandomSeed func(string)) podControls {
 
 	var controls podControls
 
@@ -216,27 +451,158 @@ func addHTMLControls(document js.Value, callbackHistorySize func(string)) podCon
 	controls.sliderCPUUsage = getSliderControl(document, "slider-cpu-usage", "textbox-cpu-usage")
 	controls.sliderPODCPURequest = getSliderControl(document, "slider-pod-cpu-request", "textbox-pod-cpu-request")
 	controls.sliderPODCPULimit = getSliderControl(document, "slider-pod-cpu-limit", "textbox-pod-cpu-limit")
+	controls.sliderResizeDelay = getSliderControl(document, "slider-resize-delay", "textbox-resize-delay")
+	controls.sliderLoadBurstiness = getSliderControl(document, "slider-load-burstiness", "textbox-load-burstiness")
+	controls.selectQoSClass = document.Call("getElementById", "select-qos-class")
 	controls.sliderHPAMinReplicas = getSliderControl(document, "slider-hpa-min-replicas", "textbox-hpa-min-replicas")
 	controls.sliderHPAMaxReplicas = getSliderControl(document, "slider-hpa-max-replicas", "textbox-hpa-max-replicas")
 	controls.sliderHPATargetCPUUtilization = getSliderControl(document, "slider-hpa-target-cpu", "textbox-hpa-target-cpu")
 	controls.sliderNumberOfPods = getSliderControl(document, "slider-number-of-pods", "textbox-number-of-pods")
 	controls.sliderHistorySize = getSliderControl(document, "slider-history-size", "textbox-history-size")
 	controls.sliderScaleDownStabilizationWindow = getSliderControl(document, "slider-scale-down-stabilization-window", "textbox-scale-down-stabilization-window")
+	controls.sliderPODSchedulingTime = getSliderControl(document, "slider-pod-scheduling-time", "textbox-pod-scheduling-time")
+	controls.sliderPODImagePullTime = getSliderControl(document, "slider-pod-image-pull-time", "textbox-pod-image-pull-time")
 	controls.sliderPODStartupTime = getSliderControl(document, "slider-pod-startup-time", "textbox-pod-startup-time")
+	controls.selectStartupDistribution = document.Call("getElementById", "select-startup-distribution")
+	controls.sliderStartupSpread = getSliderControl(document, "slider-startup-spread", "textbox-startup-spread")
+	controls.sliderRandomSeed = getSliderControl(document, "slider-random-seed", "textbox-random-seed")
+	controls.sliderPODPreStopTime = getSliderControl(document, "slider-pod-prestop-time", "textbox-pod-prestop-time")
 	controls.sliderPODStopTime = getSliderControl(document, "slider-pod-stop-time", "textbox-pod-stop-time")
+	controls.sliderPODGracePeriod = getSliderControl(document, "slider-pod-grace-period", "textbox-pod-grace-period")
+	controls.sliderPODStartupCPU = getSliderControl(document, "slider-pod-startup-cpu", "textbox-pod-startup-cpu")
+	controls.selectSheddingPolicy = document.Call("getElementById", "select-shedding-policy")
+	controls.sliderSheddingQueueSize = getSliderControl(document, "slider-shedding-queue-size", "textbox-shedding-queue-size")
+	controls.sliderSheddingQueueTimeout = getSliderControl(document, "slider-shedding-queue-timeout", "textbox-shedding-queue-timeout")
+	controls.selectBalancingStrategy = document.Call("getElementById", "select-balancing-strategy")
+	controls.sliderStickySkew = getSliderControl(document, "slider-sticky-skew", "textbox-sticky-skew")
+	controls.sliderSlowStartWindow = getSliderControl(document, "slider-slow-start-window", "textbox-slow-start-window")
+	controls.sliderColdCPUPenalty = getSliderControl(document, "slider-cold-cpu-penalty", "textbox-cold-cpu-penalty")
+	controls.sliderColdWarmUpTime = getSliderControl(document, "slider-cold-warm-up-time", "textbox-cold-warm-up-time")
+	controls.sliderReadinessFailure = getSliderControl(document, "slider-readiness-failure", "textbox-readiness-failure")
+	controls.sliderLivenessFailure = getSliderControl(document, "slider-liveness-failure", "textbox-liveness-failure")
+	controls.sliderStartupFailure = getSliderControl(document, "slider-startup-failure", "textbox-startup-failure")
+	controls.sliderCrashRate = getSliderControl(document, "slider-crash-rate", "textbox-crash-rate")
+	controls.sliderPODMemoryBaseline = getSliderControl(document, "slider-pod-memory-baseline", "textbox-pod-memory-baseline")
+	controls.sliderPODMemoryPerLoad = getSliderControl(document, "slider-pod-memory-per-load", "textbox-pod-memory-per-load")
+	controls.sliderPODMemoryLeakRate = getSliderControl(document, "slider-pod-memory-leak-rate", "textbox-pod-memory-leak-rate")
+	controls.sliderPODMemoryLimit = getSliderControl(document, "slider-pod-memory-limit", "textbox-pod-memory-limit")
+	controls.sliderPODMemoryRequest = getSliderControl(document, "slider-pod-memory-request", "textbox-pod-memory-request")
+	controls.sliderNodeCount = getSliderControl(document, "slider-node-count", "textbox-node-count")
+	controls.sliderNodeCPU = getSliderControl(document, "slider-node-cpu", "textbox-node-cpu")
+	controls.sliderNodeMemory = getSliderControl(document, "slider-node-memory", "textbox-node-memory")
+	controls.selectNodeAutoscaler = document.Call("getElementById", "select-node-autoscaler")
+	controls.sliderCAMaxNodes = getSliderControl(document, "slider-ca-max-nodes", "textbox-ca-max-nodes")
+	controls.sliderCAProvisioningDelay = getSliderControl(document, "slider-ca-provisioning-delay", "textbox-ca-provisioning-delay")
+	controls.sliderCAUnneededTime = getSliderControl(document, "slider-ca-unneeded-time", "textbox-ca-unneeded-time")
+	controls.sliderCAUtilizationThreshold = getSliderControl(document, "slider-ca-utilization-threshold", "textbox-ca-utilization-threshold")
+	controls.selectConsolidationPolicy = document.Call("getElementById", "select-consolidation-policy")
+	controls.sliderConsolidateAfter = getSliderControl(document, "slider-consolidate-after", "textbox-consolidate-after")
+	controls.textInstanceCatalog = document.Call("getElementById", "text-instance-catalog")
+	controls.sliderPlaceholderPods = getSliderControl(document, "slider-placeholder-pods", "textbox-placeholder-pods")
+	controls.sliderPlaceholderCPURequest = getSliderControl(document, "slider-placeholder-cpu-request", "textbox-placeholder-cpu-request")
+	controls.sliderPlaceholderMemoryRequest = getSliderControl(document, "slider-placeholder-memory-request", "textbox-placeholder-memory-request")
+	controls.sliderWorkloads = getSliderControl(document, "slider-workloads", "textbox-workloads")
+	controls.selectWorkload = document.Call("getElementById", "select-workload")
+	controls.sliderWorkloadPriority = getSliderControl(document, "slider-workload-priority", "textbox-workload-priority")
+	controls.selectPreemptionPolicy = document.Call("getElementById", "select-preemption-policy")
+	controls.sliderQuotaPods = getSliderControl(document, "slider-quota-pods", "textbox-quota-pods")
+	controls.sliderQuotaRequestsCPU = getSliderControl(document, "slider-quota-requests-cpu", "textbox-quota-requests-cpu")
+	controls.sliderQuotaLimitsCPU = getSliderControl(document, "slider-quota-limits-cpu", "textbox-quota-limits-cpu")
+	controls.sliderMaxSurge = getSliderControl(document, "slider-max-surge", "textbox-max-surge")
+	controls.sliderMaxUnavailable = getSliderControl(document, "slider-max-unavailable", "textbox-max-unavailable")
+	controls.buttonDeployNewVersion = document.Call("getElementById", "button-deploy-new-version")
+	controls.sliderCPUCost = getSliderControl(document, "slider-cpu-cost", "textbox-cpu-cost")
+	controls.selectTrafficSplit = document.Call("getElementById", "select-traffic-split")
+	controls.sliderCanaryWeight = getSliderControl(document, "slider-canary-weight", "textbox-canary-weight")
+	controls.selectPDBPolicy = document.Call("getElementById", "select-pdb-policy")
+	controls.sliderPDBValue = getSliderControl(document, "slider-pdb-value", "textbox-pdb-value")
+	controls.sliderMaintenanceTime = getSliderControl(document, "slider-maintenance-time", "textbox-maintenance-time")
+	controls.buttonDrainNode = document.Call("getElementById", "button-drain-node")
+	controls.buttonClusterUpgrade = document.Call("getElementById", "button-cluster-upgrade")
+	controls.sliderPodKillRate = getSliderControl(document, "slider-pod-kill-rate", "textbox-pod-kill-rate")
+	controls.sliderKillPods = getSliderControl(document, "slider-kill-pods", "textbox-kill-pods")
+	controls.buttonKillPods = document.Call("getElementById", "button-kill-pods")
+	controls.sliderSpotRate = getSliderControl(document, "slider-spot-rate", "textbox-spot-rate")
+	controls.sliderSpotFraction = getSliderControl(document, "slider-spot-fraction", "textbox-spot-fraction")
+	controls.sliderSpotNotice = getSliderControl(document, "slider-spot-notice", "textbox-spot-notice")
+	controls.buttonSpotInterruption = document.Call("getElementById", "button-spot-interruption")
+	controls.selectWorkloadKind = document.Call("getElementById", "select-workload-kind")
+	controls.selectPodManagementPolicy = document.Call("getElementById", "select-pod-management-policy")
+	controls.sliderZones = getSliderControl(document, "slider-zones", "textbox-zones")
+	controls.selectOutageZone = document.Call("getElementById", "select-outage-zone")
+	controls.sliderOutageDuration = getSliderControl(document, "slider-outage-duration", "textbox-outage-duration")
+	controls.buttonZoneOutage = document.Call("getElementById", "button-zone-outage")
+	controls.selectSpreadPolicy = document.Call("getElementById", "select-spread-policy")
+	controls.sliderMaxSkew = getSliderControl(document, "slider-max-skew", "textbox-max-skew")
+	controls.selectVPAMode = document.Call("getElementById", "select-vpa-mode")
+	controls.selectVPAResources = document.Call("getElementById", "select-vpa-resources")
 
 	// Setup synchronization between sliders and textboxes
 	setupSliderSync(controls.sliderCPUUsage, nil)
 	setupSliderSync(controls.sliderPODCPURequest, nil)
 	setupSliderSync(controls.sliderPODCPULimit, nil)
+	setupSliderSync(controls.sliderResizeDelay, nil)
+	setupSliderSync(controls.sliderLoadBurstiness, nil)
 	setupSliderSync(controls.sliderHPAMinReplicas, nil)
 	setupSliderSync(controls.sliderHPAMaxReplicas, nil)
 	setupSliderSync(controls.sliderHPATargetCPUUtilization, nil)
 	setupSliderSync(controls.sliderNumberOfPods, nil)
 	setupSliderSync(controls.sliderHistorySize, callbackHistorySize)
 	setupSliderSync(controls.sliderScaleDownStabilizationWindow, nil)
+	setupSliderSync(controls.sliderPODSchedulingTime, nil)
+	setupSliderSync(controls.sliderPODImagePullTime, nil)
 	setupSliderSync(controls.sliderPODStartupTime, nil)
+	setupSliderSync(controls.sliderStartupSpread, nil)
+	setupSliderSync(controls.sliderRandomSeed, callbackRandomSeed)
+	setupSliderSync(controls.sliderPODPreStopTime, nil)
 	setupSliderSync(controls.sliderPODStopTime, nil)
+	setupSliderSync(controls.sliderPODGracePeriod, nil)
+	setupSliderSync(controls.sliderPODStartupCPU, nil)
+	setupSliderSync(controls.sliderSheddingQueueSize, nil)
+	setupSliderSync(controls.sliderSheddingQueueTimeout, nil)
+	setupSliderSync(controls.sliderStickySkew, nil)
+	setupSliderSync(controls.sliderSlowStartWindow, nil)
+	setupSliderSync(controls.sliderColdCPUPenalty, nil)
+	setupSliderSync(controls.sliderColdWarmUpTime, nil)
+	setupSliderSync(controls.sliderReadinessFailure, nil)
+	setupSliderSync(controls.sliderLivenessFailure, nil)
+	setupSliderSync(controls.sliderStartupFailure, nil)
+	setupSliderSync(controls.sliderCrashRate, nil)
+	setupSliderSync(controls.sliderPODMemoryBaseline, nil)
+	setupSliderSync(controls.sliderPODMemoryPerLoad, nil)
+	setupSliderSync(controls.sliderPODMemoryLeakRate, nil)
+	setupSliderSync(controls.sliderPODMemoryLimit, nil)
+	setupSliderSync(controls.sliderPODMemoryRequest, nil)
+	setupSliderSync(controls.sliderNodeCount, nil)
+	setupSliderSync(controls.sliderNodeCPU, nil)
+	setupSliderSync(controls.sliderNodeMemory, nil)
+	setupSliderSync(controls.sliderCAMaxNodes, nil)
+	setupSliderSync(controls.sliderCAProvisioningDelay, nil)
+	setupSliderSync(controls.sliderCAUnneededTime, nil)
+	setupSliderSync(controls.sliderCAUtilizationThreshold, nil)
+	setupSliderSync(controls.sliderConsolidateAfter, nil)
+	setupSliderSync(controls.sliderPlaceholderPods, nil)
+	setupSliderSync(controls.sliderPlaceholderCPURequest, nil)
+	setupSliderSync(controls.sliderPlaceholderMemoryRequest, nil)
+	setupSliderSync(controls.sliderWorkloads, nil)
+	setupSliderSync(controls.sliderWorkloadPriority, nil)
+	setupSliderSync(controls.sliderQuotaPods, nil)
+	setupSliderSync(controls.sliderQuotaRequestsCPU, nil)
+	setupSliderSync(controls.sliderQuotaLimitsCPU, nil)
+	setupSliderSync(controls.sliderMaxSurge, nil)
+	setupSliderSync(controls.sliderMaxUnavailable, nil)
+	setupSliderSync(controls.sliderCPUCost, nil)
+	setupSliderSync(controls.sliderCanaryWeight, nil)
+	setupSliderSync(controls.sliderPDBValue, nil)
+	setupSliderSync(controls.sliderMaintenanceTime, nil)
+	setupSliderSync(controls.sliderPodKillRate, nil)
+	setupSliderSync(controls.sliderKillPods, nil)
+	setupSliderSync(controls.sliderSpotRate, nil)
+	setupSliderSync(controls.sliderSpotFraction, nil)
+	setupSliderSync(controls.sliderSpotNotice, nil)
+	setupSliderSync(controls.sliderZones, nil)
+	setupSliderSync(controls.sliderOutageDuration, nil)
+	setupSliderSync(controls.sliderMaxSkew, nil)
 
 	return controls
 }
@@ -244,7 +610,7 @@ func addHTMLControls(document js.Value, callbackHistorySize func(string)) podCon
 func getSliderControl(document js.Value, sliderID, textboxID string) sliderControl {
 	slider := document.Call("getElementById", sliderID)
 	textBox := document.Call("getElementById", textboxID)
-	return sliderControl{slider: slider, textBox: textBox}
+	return sliderControl{id: sliderID, slider: slider, textBox: textBox}
 }
 
 func setupSliderSync(control sliderControl, callback func(string)) {
@@ -267,224 +633,3 @@ func setupSliderSync(control sliderControl, callback func(string)) {
 		return nil
 	}))
 }
-
-func updateChart(c *chart, newPodValue, starting, stopping, newPodLoad, newUnmetLoad int) {
-
-	last := len(c.pods.data) - 1
-
-	// shift left
-	for i := 0; i < last; i++ {
-		c.pods.data[i] = c.pods.data[i+1]
-		c.podsStarting.data[i] = c.podsStarting.data[i+1]
-		c.podsStopping.data[i] = c.podsStopping.data[i+1]
-		c.podsLoad.data[i] = c.podsLoad.data[i+1]
-		c.unmetLoad.data[i] = c.unmetLoad.data[i+1]
-	}
-
-	// add new value at the end
-	c.pods.data[last] = newPodValue
-	c.podsStarting.data[last] = starting
-	c.podsStopping.data[last] = stopping
-	c.podsLoad.data[last] = newPodLoad
-	c.unmetLoad.data[last] = newUnmetLoad
-}
-
-func (c *chart) resizeHistory(newSize int) {
-	if newSize == len(c.pods.data) {
-		// no change
-		return
-	}
-
-	c.pods.data = resizeSliceInt(c.pods.data, newSize)
-	c.podsStarting.data = resizeSliceInt(c.podsStarting.data, newSize)
-	c.podsStopping.data = resizeSliceInt(c.podsStopping.data, newSize)
-	c.podsLoad.data = resizeSliceInt(c.podsLoad.data, newSize)
-	c.unmetLoad.data = resizeSliceInt(c.unmetLoad.data, newSize)
-}
-
-func resizeSliceInt(oldSlice []int, newSize int) []int {
-	if newSize == len(oldSlice) {
-		// no change
-		return oldSlice
-	}
-
-	newSlice := make([]int, newSize)
-
-	// copy existing data to new slice
-	copySize := min(len(oldSlice), newSize)
-
-	copy(newSlice[newSize-copySize:], oldSlice[len(oldSlice)-copySize:])
-
-	return newSlice
-}
-
-func newChart(ctxPods, ctxPodsLoad, ctxUnmetLoad,
-	legendPods, legendPodsLoad, legendsUnmetLoad js.Value,
-	canvasWidth, canvasHeight, historySize int) chart {
-	c := chart{
-		pods:         subchart{ctx: ctxPods, legend: legendPods, data: make([]int, historySize)},
-		podsStarting: subchart{ctx: ctxPods, data: make([]int, historySize)},
-		podsStopping: subchart{ctx: ctxPods, data: make([]int, historySize)},
-		podsLoad:     subchart{ctx: ctxPodsLoad, legend: legendPodsLoad, data: make([]int, historySize)},
-		unmetLoad:    subchart{ctx: ctxUnmetLoad, legend: legendsUnmetLoad, data: make([]int, historySize)},
-		canvasWidth:  canvasWidth,
-		canvasHeight: canvasHeight,
-	}
-
-	// fill pods with 1 (only for replicas)
-	for i := range historySize {
-		c.pods.data[i] = 1
-	}
-
-	return c
-}
-
-func drawCharts(ctxReplicas, ctxPodLoad, ctxUnmetLoad js.Value, c chart) {
-	const drawLabels = true
-	const hideLabels = false
-
-	clearChart(ctxReplicas, c)
-	{
-		lo, hi := findMinMax(append(c.pods.data, append(c.podsStarting.data, c.podsStopping.data...)...))
-		drawOneChart(ctxReplicas, c.pods.legend, c, c.pods.data, "blue", drawLabels, 6, lo, hi)
-		drawOneChart(ctxReplicas, js.Null(), c, c.podsStarting.data, "green", hideLabels, 4, lo, hi)
-		drawOneChart(ctxReplicas, js.Null(), c, c.podsStopping.data, "red", hideLabels, 2, lo, hi)
-	}
-
-	clearChart(ctxPodLoad, c)
-	{
-		lo, hi := findMinMax(c.podsLoad.data)
-		drawOneChart(ctxPodLoad, c.podsLoad.legend, c, c.podsLoad.data, "blue", drawLabels, 2, lo, hi)
-	}
-
-	clearChart(ctxUnmetLoad, c)
-	{
-		lo, hi := findMinMax(c.unmetLoad.data)
-		drawOneChart(ctxUnmetLoad, c.unmetLoad.legend, c, c.unmetLoad.data, "blue", drawLabels, 2, lo, hi)
-	}
-}
-
-func findMinMax(data []int) (int, int) {
-	maxPods := 0
-	minPods := math.MaxInt // NaN
-	for _, v := range data {
-		if v > maxPods {
-			maxPods = v
-		}
-		if v < minPods {
-			minPods = v
-		}
-	}
-	return minPods, maxPods
-}
-
-func clearChart(ctx js.Value, c chart) {
-	ctx.Set("fillStyle", "white")
-	ctx.Call("fillRect", 0, 0, c.canvasWidth, c.canvasHeight)
-}
-
-func drawOneChart(ctx, legend js.Value, c chart, data []int,
-	color string, drawLabels bool,
-	width,
-	minPods, maxPods int) {
-
-	// draw a border
-	/*
-		ctx.Set("strokeStyle", "black")
-		ctx.Set("lineWidth", 2)
-		ctx.Call("strokeRect", 0, 0, c.canvasWidth, c.canvasHeight)
-	*/
-
-	// find max pod value
-	/*
-		maxPods := 0
-		minPods := math.MaxInt // NaN
-		for _, v := range data {
-			if v > maxPods {
-				maxPods = v
-			}
-			if v < minPods {
-				minPods = v
-			}
-		}
-	*/
-
-	// pod space x ranges from 0 to len(c.pods)
-	// pod space y ranges from 0 to maxPods
-	// canvas space x ranges from 0 to c.canvasWidth
-	// canvas space y ranges from 0 to c.canvasHeight
-
-	// draw line
-	ctx.Set("strokeStyle", color)
-	ctx.Set("lineWidth", width)
-	ctx.Call("beginPath")
-
-	/*
-		maxPodsShifted := maxPods - chartVerticalMinimum
-	*/
-	// avoid division by zero
-	if maxPods <= 0 {
-		maxPods = 1
-	}
-
-	// now draw considering chartVerticalMinimum
-
-	for i, v := range data {
-		// map pod space to canvas space
-		x := i * c.canvasWidth / len(data)
-		//y := c.canvasHeight - ((v - chartVerticalMinimum) * c.canvasHeight / maxPodsShifted) // invert y axis
-		y := c.canvasHeight - (v * c.canvasHeight / maxPods) // invert y axis
-
-		if i == 0 {
-			ctx.Call("moveTo", x, y)
-		} else {
-			ctx.Call("lineTo", x, y)
-		}
-	}
-	ctx.Call("stroke")
-
-	if drawLabels {
-		// Draw a label for max replicas at top-left corner
-		labelText := fmt.Sprintf("Max: %d", maxPods)
-		ctx.Set("font", "16px Arial")
-		ctx.Set("fillStyle", "black")
-		ctx.Call("fillText", labelText, 10, 20)
-
-		// Draw a label for latest replicas count at right size
-		// But vertically aligned with the last point
-		latestReplicas := data[len(data)-1]
-
-		labelText = fmt.Sprintf("Cur: %d", latestReplicas)
-		textMetrics := ctx.Call("measureText", labelText)
-		textWidth := textMetrics.Get("width").Float()
-
-		x := c.canvasWidth - int(textWidth) - 5
-		y := c.canvasHeight - (latestReplicas * c.canvasHeight / maxPods)
-		// Move y slight up to avoid overlapping with the line
-		y -= 10
-		// Adjust y to avoid drawing outside canvas
-		if y < 20 {
-			y = 20
-		}
-		if y > c.canvasHeight-10 {
-			y = c.canvasHeight - 10
-		}
-
-		ctx.Call("fillText", labelText, x, y)
-
-		// Draw label with min, max, current replicas into legend element
-		if !legend.IsNull() {
-			var minPodsStr string
-			if minPods == math.MaxInt {
-				minPodsStr = "N/A"
-			} else {
-				minPodsStr = fmt.Sprintf("%d", minPods)
-			}
-
-			// Update specific elements instead of replacing innerHTML
-			legend.Call("querySelector", ".legend-min").Set("innerText", minPodsStr)
-			legend.Call("querySelector", ".legend-max").Set("innerText", fmt.Sprintf("%d", maxPods))
-			legend.Call("querySelector", ".legend-current").Set("innerText", fmt.Sprintf("%d", latestReplicas))
-		}
-	}
-}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// evictionRetryInterval is how often the evictions blocked by a
+// disruption budget are retried, like kubectl drain does.
+const evictionRetryInterval = 5 * time.Second
+
+// maintenance takes nodes down one at a time, for a node maintenance
+// or a cluster upgrade, like kubectl drain followed by kubectl uncordon:
+// the node is cordoned, its pods are evicted subject to their
+// PodDisruptionBudgets, and once empty the node goes down for downTime
+// before rejoining the cluster. The ReplicaSets recreate the evicted pods
+// on the other nodes.
+//
+// See: https://kubernetes.io/docs/tasks/administer-cluster/safely-drain-node/
+type maintenance struct {
+	operation   string // maintenance or upgrade, empty when idle
+	queue       []int  // ids of the nodes waiting for maintenance, the first one in progress
+	total       int    // nodes of the current operation
+	down        bool   // the first node in the queue is down
+	downTime    time.Duration
+	lastAttempt time.Time
+	start       time.Time
+}
+
+// drainNode starts the maintenance of the first ready node.
+func (m *maintenance) drainNode(c *cluster, now time.Time) {
+	if m.operation != "" {
+		return // one operation at a time
+	}
+	for _, n := range c.nodes {
+		if n.status(now) == nodeStatusReady {
+			m.begin("maintenance", []int{n.id}, now)
+			return
+		}
+	}
+}
+
+// upgradeCluster starts the maintenance of every node, one at a time.
+func (m *maintenance) upgradeCluster(c *cluster, now time.Time) {
+	if m.operation != "" {
+		return // one operation at a time
+	}
+	var ids []int
+	for _, n := range c.nodes {
+		ids = append(ids, n.id)
+	}
+	m.begin("upgrade", ids, now)
+}
+
+func (m *maintenance) begin(operation string, ids []int, now time.Time) {
+	if len(ids) == 0 {
+		return
+	}
+	m.operation = operation
+	m.queue = ids
+	m.total = len(ids)
+	m.down = false
+	m.start = now
+}
+
+// next moves on to the next node in the queue.
+func (m *maintenance) next(c *cluster, now time.Time) {
+	m.queue = m.queue[1:]
+	m.down = false
+	if len(m.queue) == 0 {
+		c.events.emit(now, "Normal", "MaintenanceComplete", "cluster",
+			fmt.Sprintf("Completed %s of %d node(s) in %v", m.operation, m.total, now.Sub(m.start).Round(time.Second)))
+		m.operation = ""
+	}
+}
+
+// run takes the maintenance of the node in progress one step further.
+func (m *maintenance) run(c *cluster, now time.Time) {
+	for len(m.queue) > 0 {
+		n := c.findNode(m.queue[0])
+		switch {
+		case n == nil || n.draining || !n.interruptAt.IsZero():
+			m.next(c, now) // removed by the node autoscaler or interrupted meanwhile
+			continue
+		case m.down:
+			if n.status(now) != nodeStatusReady {
+				return
+			}
+			c.events.emit(now, "Normal", "NodeReady", "node/"+nodeName(n.id),
+				fmt.Sprintf("Node %s status is now: NodeReady", nodeName(n.id)))
+			m.next(c, now)
+			continue
+		case !n.cordoned:
+			if n.status(now) != nodeStatusReady {
+				return // wait for the node to be ready
+			}
+			n.cordoned = true
+			m.lastAttempt = time.Time{}
+			c.events.emit(now, "Normal", "NodeNotSchedulable", "node/"+nodeName(n.id),
+				fmt.Sprintf("Node %s status is now: NodeNotSchedulable", nodeName(n.id)))
+		}
+		m.evict(c, n, now)
+		return
+	}
+}
+
+// evict evicts the pods of the cordoned node every evictionRetryInterval,
+// and takes the node down once no pod is left.
+func (m *maintenance) evict(c *cluster, n *node, now time.Time) {
+	if now.Sub(m.lastAttempt) < evictionRetryInterval {
+		return
+	}
+	m.lastAttempt = now
+	if c.evictPods(n, now) > 0 {
+		return
+	}
+	n.cordoned = false
+	n.readyAt = now.Add(m.downTime)
+	m.down = true
+	c.events.emit(now, "Normal", "NodeDrained", "node/"+nodeName(n.id),
+		fmt.Sprintf("Drained node, down for %s for %v", m.operation, m.downTime))
+}
+
+// status describes the maintenance in progress.
+func (m *maintenance) status(c *cluster) string {
+	if m.operation == "" {
+		return "idle"
+	}
+	done := m.total - len(m.queue)
+	n := c.findNode(m.queue[0])
+	step := "waiting for node"
+	switch {
+	case n == nil:
+	case m.down:
+		step = fmt.Sprintf("%s down", nodeName(n.id))
+	case n.cordoned:
+		step = fmt.Sprintf("draining %s, %d pods left", nodeName(n.id), c.podsOn(n.id))
+	}
+	return fmt.Sprintf("%s %d/%d: %s", m.operation, done, m.total, step)
+}
+
+// podsOn returns the number of pods holding resources on the node.
+func (c *cluster) podsOn(id int) int {
+	var count int
+	for _, p := range c.pods() {
+		if p.node == id && p.holdsResources() {
+			count++
+		}
+	}
+	return count
+}
+
+func nodeName(id int) string {
+	return fmt.Sprintf("node-%d", id)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// memoryModel is the memory used by a container.
+type memoryModel struct {
+	memoryBaseline float64 // MiB used by a running container
+	memoryPerLoad  float64 // MiB per mCore of load being served
+	memoryLeakRate float64 // MiB leaked per minute since the container started
+}
+
+// hasContainer tells whether the pod container is running.
+func (p pod) hasContainer() bool {
+	switch p.status {
+	case podStatusNotReady, podStatusReady, podStatusTerminating:
+		return true
+	}
+	return false
+}
+
+// updateMemory computes the memory used by each pod container:
+// a baseline, plus a component proportional to the load being served
+// (requests in flight), plus what leaked since the container started.
+// A container above the memory limit is OOMKilled, and restarted
+// through the startup path like any crashed container.
+func (d *deployment) updateMemory(now time.Time) {
+	for i := range d.podList {
+		p := &d.podList[i]
+
+		if !p.hasContainer() {
+			p.memory = 0
+			continue
+		}
+
+		leaked := d.memoryLeakRate * now.Sub(p.containerStarted).Minutes()
+		p.memory = d.memoryBaseline + d.memoryPerLoad*p.load + leaked
+
+		if p.status == podStatusTerminating || d.memoryLimit <= 0 || p.memory <= d.memoryLimit {
+			continue
+		}
+
+		d.cluster.events.emit(now, "Warning", "OOMKilled", "pod/"+d.podName(*p),
+			fmt.Sprintf("Container exceeded its memory limit: memory=%.0fMi limit=%.0fMi", p.memory, d.memoryLimit))
+		p.memory = 0
+		d.crashContainer(p, now)
+	}
+}
+
+// memoryStats returns the min, average and max memory among pods with a running container.
+func (d *deployment) memoryStats() (lo, avg, hi float64) {
+	var pods int
+	lo = math.MaxFloat64
+	for _, p := range d.podList {
+		if !p.hasContainer() {
+			continue
+		}
+		pods++
+		avg += p.memory
+		lo = min(lo, p.memory)
+		hi = max(hi, p.memory)
+	}
+	if pods == 0 {
+		return 0, 0, 0
+	}
+	return lo, avg / float64(pods), hi
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"syscall/js"
+	"time"
+)
+
+// placeholderPriority is the PriorityClass value of the overprovisioning
+// placeholder pods: any regular pod (priority 0) preempts them.
+const placeholderPriority = -1
+
+// priorityClass is the PriorityClass of the deployment pods.
+type priorityClass struct {
+	priority     int  // PriorityClass value of the pods, higher preempts lower
+	preemptLower bool // preemptionPolicy PreemptLowerPriority, rather than Never
+}
+
+// scaleUpTracker measures how long a scale up takes to get all pods ready.
+type scaleUpTracker struct {
+	scaleUpStart   time.Time     // when the scale up being tracked started, zero if none
+	scaleUpLatency time.Duration // time taken by the latest scale up to get all pods ready
+}
+
+// newPlaceholders creates the overprovisioning deployment: pause pods
+// that reserve capacity, start at once and stop at once when preempted.
+func newPlaceholders(c *cluster, rng *rand.Rand) *deployment {
+	return &deployment{
+		name:          "overprovisioning",
+		cluster:       c,
+		priorityClass: priorityClass{priority: placeholderPriority},
+		rng:           rng,
+	}
+}
+
+// preempt looks for a ready node where evicting pods of lower priority
+// makes room for the pod, picking the node with the fewest victims,
+// and evicts them through the terminating path. The pod is nominated
+// to the node and stays pending until the victims are gone.
+// Pods of the owner deployment share its PriorityClass, hence they are
+// never victims of each other.
+func (c *cluster) preempt(p *pod, owner *deployment, now time.Time) {
+	if p.nominated != 0 && c.hasVictimsTerminating(p.nominated, p.priority) {
+		return // wait for the previous victims
+	}
+	p.nominated = 0
+
+	var best []*pod
+	for _, n := range c.nodes {
+		if n.status(now) != nodeStatusReady {
+			continue
+		}
+		victims, ok := c.victims(n, *p, owner)
+		if ok && (p.nominated == 0 || len(victims) < len(best)) {
+			p.nominated = n.id
+			best = victims
+		}
+	}
+
+	for _, v := range best {
+		v.setStatus(podStatusTerminating, now)
+		c.preemptions++
+	}
+}
+
+// victims returns the pods to evict from the node to make room for the pod,
+// lowest priority first. It returns false when evicting every pod of lower
+// priority still leaves no room.
+func (c *cluster) victims(n node, p pod, owner *deployment) ([]*pod, bool) {
+	var candidates []*pod
+	for _, w := range c.workloads {
+		if w == owner {
+			continue
+		}
+		for i := range w.podList {
+			q := &w.podList[i]
+			if q.node == n.id && q.holdsResources() && q.priority < p.priority &&
+				q.status != podStatusTerminating {
+				candidates = append(candidates, q)
+			}
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b *pod) int {
+		return cmp.Compare(a.priority, b.priority)
+	})
+
+	var victims []*pod
+	for _, q := range candidates {
+		if n.fits(p.cpuRequest, p.memoryRequest) {
+			break
+		}
+		n.cpuRequested -= q.cpuRequest
+		n.memoryRequested -= q.memoryRequest
+		victims = append(victims, q)
+	}
+
+	return victims, n.fits(p.cpuRequest, p.memoryRequest)
+}
+
+// nominatedRequests returns the requests of the pods of higher priority
+// nominated to the node, waiting for their victims to go away.
+func (c *cluster) nominatedRequests(nodeID, priority int) (cpu, memory float64) {
+	for _, q := range c.pods() {
+		if q.nominated == nodeID && q.priority > priority {
+			cpu += q.cpuRequest
+			memory += q.memoryRequest
+		}
+	}
+	return cpu, memory
+}
+
+// hasVictimsTerminating tells whether pods of lower priority are still
+// terminating on the node.
+func (c *cluster) hasVictimsTerminating(nodeID, priority int) bool {
+	for _, q := range c.pods() {
+		if q.node == nodeID && q.priority < priority && q.status == podStatusTerminating {
+			return true
+		}
+	}
+	return false
+}
+
+// trackScaleUp measures the scale up latency: the time from the replica
+// count increase until as many pods are ready.
+func (d *deployment) trackScaleUp(ready int, now time.Time) {
+	if d.scaleUpStart.IsZero() || ready < d.desiredReplicas {
+		return
+	}
+	d.scaleUpLatency = now.Sub(d.scaleUpStart)
+	d.scaleUpStart = time.Time{}
+}
+
+// updateOverprovisioningLegend shows the scale up latency, ready placeholders and preemptions.
+func updateOverprovisioningLegend(legend js.Value, d, placeholders *deployment) {
+	legend.Call("querySelector", ".scale-up-latency").Set("innerText",
+		d.scaleUpLatency.Round(time.Second).String())
+	legend.Call("querySelector", ".placeholders-ready").Set("innerText",
+		fmt.Sprintf("%d/%d", placeholders.countStatus(podStatusReady), placeholders.desiredReplicas))
+	legend.Call("querySelector", ".preemptions").Set("innerText",
+		fmt.Sprintf("%d", d.cluster.preemptions))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"syscall/js"
+	"time"
+)
+
+// pdbPolicy is how the PodDisruptionBudget of a deployment is expressed.
+type pdbPolicy string
+
+const (
+	pdbNone           pdbPolicy = "none"           // no budget, evictions always allowed
+	pdbMinAvailable   pdbPolicy = "minAvailable"   // pods that must stay ready
+	pdbMaxUnavailable pdbPolicy = "maxUnavailable" // pods that may be unavailable
+)
+
+// podDisruptionBudget limits the voluntary disruptions, like the evictions
+// of a node drain, of the pods of a deployment. The budget is a percentage
+// of the desired replicas, rounded up like the disruption controller does.
+//
+// See: https://kubernetes.io/docs/concepts/workloads/pods/disruptions/
+type podDisruptionBudget struct {
+	policy  pdbPolicy
+	percent float64 // fraction of the desired replicas
+}
+
+// desiredHealthy returns how many of the expected pods must stay ready.
+func (b podDisruptionBudget) desiredHealthy(expected int) int {
+	n := int(math.Ceil(b.percent * float64(expected)))
+	switch b.policy {
+	case pdbMinAvailable:
+		return n
+	case pdbMaxUnavailable:
+		return max(expected-n, 0)
+	}
+	return 0
+}
+
+// disruptionsAllowed returns how many ready pods of the deployment
+// can be evicted now without violating its budget.
+func (d *deployment) disruptionsAllowed() int {
+	return max(d.countStatus(podStatusReady)-d.pdb.desiredHealthy(d.desiredReplicas), 0)
+}
+
+// evict asks the Eviction API to evict the pod of the owner deployment,
+// which then goes through the terminating path. A ready pod is evicted
+// only if the budget allows a disruption, a pod not ready yet only if
+// the budget is met (the IfHealthyBudget unhealthy pod eviction policy).
+// It returns false when the eviction would violate the budget.
+func (c *cluster) evict(p *pod, owner *deployment, now time.Time) bool {
+	healthy := owner.countStatus(podStatusReady)
+	desired := owner.pdb.desiredHealthy(owner.desiredReplicas)
+	if healthy < desired || (p.status == podStatusReady && healthy == desired) {
+		owner.pdbBlocked++
+		c.events.emit(now, "Warning", "EvictionBlocked", "poddisruptionbudget/"+owner.name,
+			"Cannot evict pod as it would violate the pod's disruption budget.")
+		return false
+	}
+	p.setStatus(podStatusTerminating, now)
+	c.evictions++
+	return true
+}
+
+// evictPods evicts the pods bound to the node through the Eviction API.
+// It returns the number of pods still holding resources on the node.
+func (c *cluster) evictPods(n *node, now time.Time) int {
+	var left int
+	for _, d := range c.workloads {
+		for i := range d.podList {
+			p := &d.podList[i]
+			if p.node != n.id || !p.holdsResources() {
+				continue
+			}
+			left++
+			if p.status != podStatusTerminating {
+				c.evict(p, d, now)
+			}
+		}
+	}
+	return left
+}
+
+// updateDisruptionLegend shows the disruptions allowed, evictions blocked and maintenance step.
+func updateDisruptionLegend(legend js.Value, d *deployment, m *maintenance, c *cluster) {
+	budget := "no budget"
+	if d.pdb.policy != pdbNone {
+		budget = fmt.Sprintf("%d (%d ready, %d desired healthy)", d.disruptionsAllowed(),
+			d.countStatus(podStatusReady), d.pdb.desiredHealthy(d.desiredReplicas))
+	}
+	legend.Call("querySelector", ".pdb-allowed").Set("innerText", budget)
+	legend.Call("querySelector", ".pdb-blocked").Set("innerText",
+		fmt.Sprintf("%d", d.pdbBlocked))
+	legend.Call("querySelector", ".maintenance-status").Set("innerText", m.status(c))
+}
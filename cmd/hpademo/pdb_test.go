@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestDesiredHealthyRoundsUp(t *testing.T) {
+	// 50% of 3 replicas is 1.5 pods, rounded up to 2 like the disruption controller
+	minAvailable := podDisruptionBudget{policy: pdbMinAvailable, percent: 0.5}
+	if got := minAvailable.desiredHealthy(3); got != 2 {
+		t.Errorf("minAvailable 50%% of 3: %d desired healthy, want 2", got)
+	}
+
+	// maxUnavailable rounds the unavailable pods up, hence fewer must stay healthy
+	maxUnavailable := podDisruptionBudget{policy: pdbMaxUnavailable, percent: 0.5}
+	if got := maxUnavailable.desiredHealthy(3); got != 1 {
+		t.Errorf("maxUnavailable 50%% of 3: %d desired healthy, want 1", got)
+	}
+	if got := (podDisruptionBudget{policy: pdbMaxUnavailable, percent: 1}).desiredHealthy(4); got != 0 {
+		t.Errorf("maxUnavailable 100%%: %d desired healthy, want 0", got)
+	}
+	if got := (podDisruptionBudget{policy: pdbNone, percent: 1}).desiredHealthy(4); got != 0 {
+		t.Errorf("no budget: %d desired healthy, want 0", got)
+	}
+}
+
+func TestEvictHonorsBudget(t *testing.T) {
+	c := &cluster{}
+	d := &deployment{
+		name:            "workload",
+		cluster:         c,
+		desiredReplicas: 3,
+		pdb:             podDisruptionBudget{policy: pdbMinAvailable, percent: 0.5}, // 2 healthy
+		podList: []pod{
+			{id: 1, node: 1, status: podStatusReady},
+			{id: 2, node: 1, status: podStatusReady},
+			{id: 3, node: 1, status: podStatusReady},
+			{id: 4, node: 1, status: podStatusNotReady},
+		},
+	}
+
+	if !c.evict(&d.podList[0], d, testNow) {
+		t.Fatal("3 ready pods, 2 desired healthy: eviction refused")
+	}
+
+	// 2 ready pods left, exactly the desired healthy: one more would violate it
+	if c.evict(&d.podList[1], d, testNow) {
+		t.Error("ready pod evicted with healthy == desired healthy")
+	}
+	if d.pdbBlocked != 1 {
+		t.Errorf("%d evictions blocked, want 1", d.pdbBlocked)
+	}
+
+	// evicting a pod not ready does not lower the healthy pods
+	if !c.evict(&d.podList[3], d, testNow) {
+		t.Error("not ready pod refused while the budget is met")
+	}
+
+	if c.evictions != 2 {
+		t.Errorf("%d evictions, want 2", c.evictions)
+	}
+	if d.podList[0].status != podStatusTerminating || d.podList[1].status != podStatusReady {
+		t.Errorf("statuses %v and %v, want the evicted pod terminating and the blocked one ready",
+			d.podList[0].status, d.podList[1].status)
+	}
+}
+
+func TestEvictUnhealthyBelowBudget(t *testing.T) {
+	c := &cluster{}
+	d := &deployment{
+		name:            "workload",
+		cluster:         c,
+		desiredReplicas: 2,
+		pdb:             podDisruptionBudget{policy: pdbMinAvailable, percent: 1},
+		podList: []pod{
+			{id: 1, node: 1, status: podStatusReady},
+			{id: 2, node: 1, status: podStatusNotReady},
+		},
+	}
+	// IfHealthyBudget: with the budget not met, even unhealthy pods stay
+	if c.evict(&d.podList[1], d, testNow) {
+		t.Error("not ready pod evicted with the budget not met")
+	}
+}
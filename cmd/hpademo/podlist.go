@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+// podName returns the pod name: the StatefulSet pod identity, or the
+// ReplicaSet name followed by the pod id.
+func (d *deployment) podName(p pod) string {
+	if p.ordinal >= 0 {
+		return d.statefulPodName(p.ordinal)
+	}
+	return fmt.Sprintf("%s-%d", d.replicaSetName(p.revision), p.id)
+}
+
+// kubectlStatus returns the pod status as shown by kubectl get pods.
+func (p pod) kubectlStatus() string {
+	switch p.status {
+	case podStatusPending:
+		return "Pending"
+	case podStatusContainerCreating:
+		return "ContainerCreating"
+	case podStatusTerminating:
+		return "Terminating"
+	case podStatusFailed:
+		return "Error"
+	case podStatusCrashLoopBackOff:
+		return "CrashLoopBackOff"
+	}
+	return "Running"
+}
+
+// humanDuration formats the age like kubectl: 45s, 3m12s, 12m, 1h5m.
+func humanDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < 2*time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < 10*time.Minute:
+		return fmt.Sprintf("%dm%ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 3*time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// updatePodList writes the pods of the deployment, oldest first,
+// into the element like kubectl get pods -o wide.
+func updatePodList(el js.Value, d *deployment, now time.Time) {
+	pods := slices.Clone(d.podList)
+	slices.SortFunc(pods, func(a, b pod) int { return a.id - b.id })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-24s %-5s %-17s %-8s %-7s %s\n", "NAME", "READY", "STATUS", "RESTARTS", "AGE", "NODE")
+	for _, p := range pods {
+		ready := "0/1"
+		if p.status == podStatusReady {
+			ready = "1/1"
+		}
+		node := "<none>"
+		if p.node != 0 {
+			node = nodeName(p.node)
+		}
+		fmt.Fprintf(&sb, "%-24s %-5s %-17s %-8d %-7s %s\n", d.podName(p), ready, p.kubectlStatus(),
+			p.restarts, humanDuration(now.Sub(p.created)), node)
+	}
+	el.Set("innerText", sb.String())
+}
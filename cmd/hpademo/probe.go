@@ -0,0 +1,34 @@
+package main
+
+import "time"
+
+// saturationThreshold is the fraction of the CPU limit above which a pod
+// is considered saturated.
+const saturationThreshold = 0.99
+
+// readinessRecoveryTime is how long a pod that failed its readiness probe
+// stays out of the serving set before the probe succeeds again
+// (periodSeconds=10, successThreshold=1).
+const readinessRecoveryTime = 10 * time.Second
+
+// probe runs the readiness and liveness probes on a ready pod.
+// A pod saturated at its CPU limit is too slow to answer the probes:
+// after readinessFail it leaves the serving set, after livenessFail
+// its container is killed and restarted. Either one may come first,
+// depending on how the probes are tuned.
+func (d *deployment) probe(p *pod, now time.Time) {
+	if p.status != podStatusReady || p.saturatedSince.IsZero() {
+		return
+	}
+
+	saturated := now.Sub(p.saturatedSince)
+
+	switch {
+	case d.livenessFail > 0 && saturated >= d.livenessFail:
+		d.crashContainer(p, now)
+	case d.readinessFail > 0 && saturated >= d.readinessFail:
+		p.readinessFailed = true
+		p.saturatedSince = time.Time{}
+		p.setStatus(podStatusNotReady, now)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+)
+
+// resourceQuota is the ResourceQuota of the namespace of a deployment.
+// Every workload runs in a namespace of its own, hence the quota limits
+// the pods of that workload only, and each workload has its own quota.
+// A zero hard limit means no quota on that resource.
+//
+// See: https://kubernetes.io/docs/concepts/policy/resource-quotas/
+type resourceQuota struct {
+	pods        int
+	requestsCPU float64 // mCores
+	limitsCPU   float64 // mCores
+}
+
+// quotaUsage is the amount of the quota consumed by the pods of a workload,
+// the only ones in its namespace.
+type quotaUsage struct {
+	pods        int
+	requestsCPU float64
+	limitsCPU   float64
+}
+
+// workloadQuotaUsed returns the quota consumed by the pods of the workload.
+// Pods in a terminal phase (Failed) do not count against the quota,
+// while Terminating pods do until they are gone.
+func workloadQuotaUsed(pods []pod) quotaUsage {
+	var used quotaUsage
+	for _, p := range pods {
+		if p.status == podStatusFailed {
+			continue
+		}
+		used.pods++
+		used.requestsCPU += p.cpuRequest
+		used.limitsCPU += p.cpuLimit
+	}
+	return used
+}
+
+// admit returns an error like the quota admission plugin
+// when creating a pod with the given CPU request and limit exceeds the quota,
+// or when the pod does not specify a resource the quota constrains.
+func (q resourceQuota) admit(used quotaUsage, cpuRequest, cpuLimit float64) error {
+	var unspecified []string
+	if q.requestsCPU > 0 && cpuRequest <= 0 {
+		unspecified = append(unspecified, "requests.cpu")
+	}
+	if q.limitsCPU > 0 && cpuLimit <= 0 {
+		unspecified = append(unspecified, "limits.cpu")
+	}
+	if len(unspecified) > 0 {
+		return fmt.Errorf("failed quota: compute-resources: must specify %s", strings.Join(unspecified, ","))
+	}
+
+	var requested, usedList, limited []string
+	check := func(name string, request, used, hard float64, format func(float64) string) {
+		if hard <= 0 || used+request <= hard {
+			return
+		}
+		requested = append(requested, name+"="+format(request))
+		usedList = append(usedList, name+"="+format(used))
+		limited = append(limited, name+"="+format(hard))
+	}
+	count := func(v float64) string { return fmt.Sprintf("%d", int(v)) }
+	mCores := func(v float64) string { return fmt.Sprintf("%dm", int(v)) }
+
+	check("pods", 1, float64(used.pods), float64(q.pods), count)
+	check("requests.cpu", cpuRequest, used.requestsCPU, q.requestsCPU, mCores)
+	check("limits.cpu", cpuLimit, used.limitsCPU, q.limitsCPU, mCores)
+
+	if len(requested) == 0 {
+		return nil
+	}
+	return fmt.Errorf("exceeded quota: compute-resources, requested: %s, used: %s, limited: %s",
+		strings.Join(requested, ","), strings.Join(usedList, ","), strings.Join(limited, ","))
+}
+
+// updateQuotaLegend shows the quota used against the hard limits and the FailedCreate count.
+func updateQuotaLegend(legend js.Value, d *deployment) {
+	used := workloadQuotaUsed(d.podList)
+	hard := func(used, hard float64, unit string) string {
+		if hard <= 0 {
+			return fmt.Sprintf("%d%s (no quota)", int(used), unit)
+		}
+		return fmt.Sprintf("%d%s/%d%s", int(used), unit, int(hard), unit)
+	}
+	legend.Call("querySelector", ".quota-pods").Set("innerText",
+		hard(float64(used.pods), float64(d.quota.pods), ""))
+	legend.Call("querySelector", ".quota-requests-cpu").Set("innerText",
+		hard(used.requestsCPU, d.quota.requestsCPU, "m"))
+	legend.Call("querySelector", ".quota-limits-cpu").Set("innerText",
+		hard(used.limitsCPU, d.quota.limitsCPU, "m"))
+	legend.Call("querySelector", ".failed-creates").Set("innerText",
+		fmt.Sprintf("%d", d.failedCreates))
+}
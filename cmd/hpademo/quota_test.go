@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdmitUpToTheHardLimit(t *testing.T) {
+	q := resourceQuota{pods: 3, requestsCPU: 1500, limitsCPU: 3000}
+	used := quotaUsage{}
+	for i := range 3 {
+		if err := q.admit(used, 500, 1000); err != nil {
+			t.Fatalf("pod %d rejected within the quota: %v", i+1, err)
+		}
+		used.pods++
+		used.requestsCPU += 500
+		used.limitsCPU += 1000
+	}
+
+	err := q.admit(used, 500, 1000)
+	if err == nil {
+		t.Fatal("pod beyond the quota admitted")
+	}
+	// every exceeded resource is reported, like the quota admission plugin
+	for _, want := range []string{"exceeded quota", "pods=1", "requests.cpu=500m", "limits.cpu=3000m"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestAdmitWithoutQuota(t *testing.T) {
+	huge := quotaUsage{pods: 1000, requestsCPU: 1e6, limitsCPU: 1e6}
+	if err := (resourceQuota{}).admit(huge, 0, 0); err != nil {
+		t.Errorf("no quota, pod rejected: %v", err)
+	}
+}
+
+func TestAdmitRequiresConstrainedResources(t *testing.T) {
+	q := resourceQuota{limitsCPU: 2000}
+	err := q.admit(quotaUsage{}, 500, 0)
+	if err == nil || !strings.Contains(err.Error(), "must specify limits.cpu") {
+		t.Errorf("pod without a CPU limit under a limits.cpu quota: error %v", err)
+	}
+	if err := q.admit(quotaUsage{}, 0, 500); err != nil {
+		t.Errorf("requests.cpu is not constrained, pod rejected: %v", err)
+	}
+}
+
+func TestWorkloadQuotaUsedSkipsFailedPods(t *testing.T) {
+	allocation := podAllocation{cpuRequest: 100, cpuLimit: 200}
+	pods := []pod{
+		{status: podStatusReady, podAllocation: allocation},
+		{status: podStatusTerminating, podAllocation: allocation},
+		{status: podStatusFailed, podAllocation: allocation},
+	}
+	used := workloadQuotaUsed(pods)
+	if used != (quotaUsage{pods: 2, requestsCPU: 200, limitsCPU: 400}) {
+		t.Errorf("used %+v, want the ready and terminating pods only", used)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// slowStartInitialBatchSize is the size of the first batch of pod creations,
+// like the ReplicaSet controller SlowStartInitialBatchSize.
+const slowStartInitialBatchSize = 1
+
+// burstReplicas limits how many pods the ReplicaSet controller
+// creates or deletes in one sync.
+const burstReplicas = 500
+
+// createBatches creates n pods of the ReplicaSet in slow-start batches
+// of 1, 2, 4, 8 ... pods, like the ReplicaSet controller. A batch with
+// a failed creation (e.g. rejected by the quota) skips the remaining
+// batches, which are retried on the next sync, hence a failing pod
+// template does not flood the API server with doomed requests.
+// It returns the updated pods.
+func (d *deployment) createBatches(pods []pod, rs replicaSet, n int, now time.Time) []pod {
+	used := workloadQuotaUsed(pods)
+	cpuRequest, _, cpuLimit := d.podRequests()
+	remaining := n
+	for batch := min(remaining, slowStartInitialBatchSize); batch > 0; batch = min(2*batch, remaining) {
+		var failed int
+		for range batch {
+			if err := d.quota.admit(used, cpuRequest, cpuLimit); err != nil {
+				failed++
+				name := d.replicaSetName(rs.revision)
+				d.failedCreates++
+				d.cluster.events.emit(now, "Warning", "FailedCreate", "replicaset/"+name,
+					fmt.Sprintf("Error creating: pods %q is forbidden: %v", fmt.Sprintf("%s-%d", name, d.lastPodID+1), err))
+				continue
+			}
+			p := d.newPod(now)
+			p.revision = rs.revision
+			used.pods++
+			used.requestsCPU += p.cpuRequest
+			used.limitsCPU += p.cpuLimit
+			pods = append(pods, p)
+		}
+		remaining -= batch
+		if failed > 0 {
+			break // skip the remaining batches until the next sync
+		}
+	}
+	return pods
+}
+
+// deleteRanked deletes the n active pods ranked first for deletion.
+// Pods not bound to a node yet are deleted at once, the others go
+// through the terminating path. It returns the remaining pods.
+func (d *deployment) deleteRanked(pods []pod, active []int, n int, now time.Time) []pod {
+	colocated := map[int]int{} // active pods of the ReplicaSet on each node
+	for _, i := range active {
+		colocated[pods[i].node]++
+	}
+
+	slices.SortStableFunc(active, func(a, b int) int {
+		return compareForDeletion(pods[a], pods[b], colocated, now)
+	})
+
+	for _, i := range active[:n] {
+		if pods[i].node == 0 {
+			pods[i].deletedAt = now
+			continue
+		}
+		pods[i].setStatus(podStatusTerminating, now)
+	}
+
+	return slices.DeleteFunc(pods, func(p pod) bool {
+		if p.deletedAt.IsZero() {
+			return false
+		}
+		d.recordDeleted(p)
+		return true
+	})
+}
+
+// compareForDeletion ranks two active pods of a ReplicaSet like the
+// ReplicaSet controller picks the pods to delete when scaling down.
+// It returns a negative number when a is deleted before b:
+//
+//  1. not bound to a node before bound
+//  2. Pending (pulling the image) before Running
+//  3. not ready before ready
+//  4. more pods of the ReplicaSet on the same node first
+//  5. ready for less time first
+//  6. more container restarts first
+//  7. newer pods first
+//
+// Ready and creation times are compared on a logarithmic scale, like the
+// LogarithmicScaleDown feature, so pods of about the same age fall through
+// to the next criteria, and at last are picked in an arbitrary order, see uid.
+//
+// See: https://github.com/kubernetes/kubernetes/blob/master/pkg/controller/controller_utils.go
+func compareForDeletion(a, b pod, colocated map[int]int, now time.Time) int {
+	if c := cmp.Compare(boolRank(a.node != 0), boolRank(b.node != 0)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(boolRank(a.isRunning()), boolRank(b.isRunning())); c != 0 {
+		return c
+	}
+	aReady, bReady := a.status == podStatusReady, b.status == podStatusReady
+	if c := cmp.Compare(boolRank(aReady), boolRank(bReady)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(colocated[b.node], colocated[a.node]); c != 0 {
+		return c
+	}
+	if aReady && bReady && !a.readySince.Equal(b.readySince) {
+		if c := cmp.Compare(ageRank(a.readySince, now), ageRank(b.readySince, now)); c != 0 {
+			return c
+		}
+	}
+	if c := cmp.Compare(b.restarts, a.restarts); c != 0 {
+		return c
+	}
+	if !a.created.Equal(b.created) {
+		if c := cmp.Compare(ageRank(a.created, now), ageRank(b.created, now)); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.uid(), b.uid())
+}
+
+// uid stands for the random pod UID: a hash of the pod id
+// in an arbitrary order unrelated to the pod age.
+func (p pod) uid() uint32 {
+	return uint32(p.id) * 2654435761 // Knuth multiplicative hash
+}
+
+// isRunning tells whether the pod phase is Running:
+// its containers have been created.
+func (p pod) isRunning() bool {
+	return p.status != podStatusPending && p.status != podStatusContainerCreating
+}
+
+// ageRank returns the logarithmic rank of the time elapsed since t,
+// -1 if none has elapsed.
+func ageRank(t, now time.Time) int {
+	elapsed := now.Sub(t)
+	if elapsed <= 0 {
+		return -1
+	}
+	return int(math.Log2(float64(elapsed)))
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// readyPod returns a pod ready for the duration on the node,
+// created a minute before it became ready.
+func readyPod(id, node int, readyFor time.Duration) pod {
+	since := testNow.Add(-readyFor)
+	return pod{id: id, node: node, status: podStatusReady, podLifetime: podLifetime{readySince: since, created: since.Add(-time.Minute)}}
+}
+
+// deletionOrder ranks the pods like deleteRanked does and returns their ids,
+// first deleted first.
+func deletionOrder(pods []pod) []int {
+	colocated := map[int]int{}
+	for _, p := range pods {
+		colocated[p.node]++
+	}
+	slices.SortStableFunc(pods, func(a, b pod) int {
+		return compareForDeletion(a, b, colocated, testNow)
+	})
+	var ids []int
+	for _, p := range pods {
+		ids = append(ids, p.id)
+	}
+	return ids
+}
+
+func TestDeletionOrderByLifecycle(t *testing.T) {
+	created := podLifetime{created: testNow}
+	pods := []pod{
+		readyPod(5, 5, time.Hour),
+		{id: 3, node: 3, status: podStatusNotReady, podLifetime: created},
+		readyPod(4, 4, 10*time.Second),
+		{id: 1, status: podStatusPending, podLifetime: created},
+		{id: 2, node: 2, status: podStatusContainerCreating, podLifetime: created},
+	}
+
+	// unscheduled, pending, not ready, ready for less time, ready for long
+	want := []int{1, 2, 3, 4, 5}
+	if got := deletionOrder(pods); !slices.Equal(got, want) {
+		t.Errorf("deletion order %v, want %v", got, want)
+	}
+}
+
+func TestDeletionOrderPrefersCrowdedNode(t *testing.T) {
+	// pod 1 shares node 1 with pod 2, pod 3 is alone on node 2:
+	// one of the crowded pair goes first, even if ready for longer
+	pods := []pod{
+		readyPod(3, 2, time.Minute),
+		readyPod(1, 1, time.Hour),
+		readyPod(2, 1, time.Hour),
+	}
+	if got := deletionOrder(pods); got[2] != 3 {
+		t.Errorf("deletion order %v, want pod 3 last", got)
+	}
+}
+
+func TestDeletionSameReadyBucketFallsThrough(t *testing.T) {
+	// 100s and 120s fall in the same log2 bucket, hence the ready time
+	// does not decide. The pod ids are picked so that the arbitrary uid
+	// order disagrees with the expected order.
+	if ageRank(testNow.Add(-100*time.Second), testNow) != ageRank(testNow.Add(-120*time.Second), testNow) {
+		t.Fatal("100s and 120s should share a log2 bucket")
+	}
+
+	restarted := readyPod(1, 1, 120*time.Second)
+	restarted.restarts = 3
+	if got := deletionOrder([]pod{readyPod(2, 2, 100*time.Second), restarted}); got[0] != 1 {
+		t.Errorf("deletion order %v, want the pod with more restarts first", got)
+	}
+
+	older := readyPod(2, 1, 100*time.Second)
+	older.created = testNow.Add(-time.Hour)
+	newer := readyPod(1, 2, 120*time.Second)
+	newer.created = testNow.Add(-10 * time.Minute)
+	if got := deletionOrder([]pod{older, newer}); got[0] != 1 {
+		t.Errorf("deletion order %v, want the newer pod first", got)
+	}
+}
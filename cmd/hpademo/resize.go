@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// podResize is an in-place resize of the pod resources: the pod spec was
+// patched with new requests and limits, which the kubelet allocates to the
+// running container once resizeDelay has passed, without a restart, if
+// the node has room for them.
+//
+// See: https://kubernetes.io/docs/tasks/configure-pod-container/resize-container-resources/
+type podResize struct {
+	cpuRequest    float64 // mCores
+	memoryRequest float64 // MiB
+	cpuLimit      float64 // mCores
+	requested     time.Time
+	deferred      bool // the node had no room, the kubelet retries
+	qosRejected   bool // would change the QoS class, rejected and warned once
+}
+
+// podAllocation is the resources allocated to the pod container,
+// and the resize to new ones, if any.
+type podAllocation struct {
+	cpuRequest    float64 // mCores
+	memoryRequest float64 // MiB
+	cpuLimit      float64 // mCores
+	resize        podResize
+}
+
+// pending tells whether the pod has a resize the kubelet has not applied yet.
+func (r podResize) pending() bool {
+	return !r.requested.IsZero()
+}
+
+// podSpec returns the CPU request, memory request and CPU limit the pod
+// spec asks for: the pod template, except for the resources the VPA sets,
+// which keep the values of the pod (or of its pending resize).
+func (d *deployment) podSpec(p pod) (cpuRequest, memoryRequest, cpuLimit float64) {
+	cpuRequest, memoryRequest, cpuLimit = p.cpuRequest, p.memoryRequest, p.cpuLimit
+	if p.resize.pending() {
+		cpuRequest, memoryRequest, cpuLimit = p.resize.cpuRequest, p.resize.memoryRequest, p.resize.cpuLimit
+	}
+	if !d.vpa.controlsCPU() {
+		cpuRequest, cpuLimit = d.cpuRequest, d.cpuLimit
+	}
+	if !d.vpa.controlsMemory() {
+		memoryRequest = d.memoryRequest
+	}
+	return cpuRequest, memoryRequest, cpuLimit
+}
+
+// requestResize patches the pod spec with the resources. The resize delay
+// starts over unless the same resize is already pending.
+func (p *pod) requestResize(cpuRequest, memoryRequest, cpuLimit float64, now time.Time) {
+	r := p.resize
+	if r.pending() && r.cpuRequest == cpuRequest && r.memoryRequest == memoryRequest && r.cpuLimit == cpuLimit {
+		return
+	}
+	p.resize = podResize{
+		cpuRequest:    cpuRequest,
+		memoryRequest: memoryRequest,
+		cpuLimit:      cpuLimit,
+		requested:     now,
+	}
+}
+
+// runResizes brings the pods to their spec when the template changes.
+// Pods not bound to a node yet take the new resources right away, as the
+// scheduler reads them from the spec. Running pods are resized in place
+// by the kubelet after the resize delay, unless the resize would change
+// their QoS class, which is not allowed. Hence changing the template does
+// not change the past: the HPA keeps dividing the usage by the requests
+// the pods actually had. A resize rejected for changing the QoS class
+// emits a warning once per pod.
+func (d *deployment) runResizes(now time.Time) {
+	for i := range d.podList {
+		p := &d.podList[i]
+		if p.status == podStatusTerminating || p.status == podStatusFailed {
+			p.resize = podResize{}
+			continue
+		}
+		cpuRequest, memoryRequest, cpuLimit := d.podSpec(*p)
+		if p.node == 0 {
+			p.cpuRequest, p.memoryRequest, p.cpuLimit = cpuRequest, memoryRequest, cpuLimit
+			p.resize = podResize{}
+			continue
+		}
+		if cpuRequest == p.cpuRequest && memoryRequest == p.memoryRequest && cpuLimit == p.cpuLimit {
+			p.resize = podResize{}
+			continue
+		}
+		if class := podQOS(cpuRequest, memoryRequest, cpuLimit, d.memoryLimit); class != d.qos(*p) {
+			// a resize must not change the QoS class, the pod keeps its resources
+			if !p.resize.qosRejected {
+				d.cluster.events.emit(now, "Warning", "ResizeRejected", "pod/"+d.podName(*p),
+					fmt.Sprintf("Resize to cpu=%dm memory=%dMi rejected: Pod QoS is immutable (%s to %s)",
+						int(cpuRequest), int(memoryRequest), d.qos(*p), class))
+			}
+			p.resize = podResize{qosRejected: true}
+			continue
+		}
+		p.requestResize(cpuRequest, memoryRequest, cpuLimit, now)
+		if now.Sub(p.resize.requested) >= d.resizeDelay {
+			d.applyResize(p, now)
+		}
+	}
+}
+
+// applyResize allocates the resources of the pending resize to the pod,
+// if its node has room for them. Otherwise the resize is deferred, or
+// infeasible if the node could never hold them, and a warning is emitted.
+func (d *deployment) applyResize(p *pod, now time.Time) {
+	n := d.cluster.findNode(p.node)
+	if n == nil {
+		return
+	}
+	r := p.resize
+	if !n.fits(r.cpuRequest-p.cpuRequest, r.memoryRequest-p.memoryRequest) {
+		if !r.deferred {
+			p.resize.deferred = true
+			reason := "Deferred: node has no room for it now"
+			if r.cpuRequest > n.cpu || r.memoryRequest > n.memory {
+				reason = "Infeasible: node allocatable is too small"
+			}
+			d.cluster.events.emit(now, "Warning", "ResizePending", "pod/"+d.podName(*p),
+				fmt.Sprintf("Resize to cpu=%dm memory=%dMi %s", int(r.cpuRequest), int(r.memoryRequest), reason))
+		}
+		return
+	}
+	n.cpuRequested += r.cpuRequest - p.cpuRequest
+	n.memoryRequested += r.memoryRequest - p.memoryRequest
+	p.cpuRequest, p.memoryRequest, p.cpuLimit = r.cpuRequest, r.memoryRequest, r.cpuLimit
+	p.resize = podResize{}
+	d.cluster.events.emit(now, "Normal", "Resized", "pod/"+d.podName(*p),
+		fmt.Sprintf("Pod resized in place to cpu=%dm memory=%dMi, cpu limit %dm",
+			int(p.cpuRequest), int(p.memoryRequest), int(p.cpuLimit)))
+}
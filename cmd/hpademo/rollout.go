@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"syscall/js"
+	"time"
+)
+
+// replicaSet is a generation of the deployment pod template.
+// Its pods are the deployment pods with the same revision.
+type replicaSet struct {
+	revision int
+	replicas int // desired replicas, set by the deployment controller
+}
+
+// rolloutState is the RollingUpdate strategy of the deployment
+// and the ReplicaSets it rolls the pods through.
+type rolloutState struct {
+	replicaSets     []replicaSet  // generations of the pod template, oldest first
+	revision        int           // revision of the newest ReplicaSet
+	scaledReplicas  int           // desired replicas the ReplicaSets were last scaled for
+	maxSurge        float64       // fraction of the desired replicas allowed above them during a rollout
+	maxUnavailable  float64       // fraction of the desired replicas allowed unavailable during a rollout
+	rolloutStart    time.Time     // when the rollout in progress started, zero if none
+	rolloutDuration time.Duration // time taken by the latest rollout
+}
+
+func (d *deployment) replicaSetName(revision int) string {
+	return fmt.Sprintf("%s-r%d", d.name, revision)
+}
+
+// newest returns the ReplicaSet of the current pod template.
+func (d *deployment) newest() *replicaSet {
+	return &d.replicaSets[len(d.replicaSets)-1]
+}
+
+// deployNewVersion changes the pod template: a new ReplicaSet generation
+// (a controller revision for a StatefulSet) is created, and the rolling
+// update moves the pods over to it.
+func (d *deployment) deployNewVersion(now time.Time) {
+	d.revision++
+	d.replicaSets = append(d.replicaSets, replicaSet{revision: d.revision})
+	d.rolloutStart = now
+	if d.kind != kindStatefulSet {
+		d.cluster.events.emit(now, "Normal", "NewReplicaSet", "deployment/"+d.name,
+			fmt.Sprintf("Created new replica set %s", d.replicaSetName(d.revision)))
+	}
+}
+
+// rollingLimits resolves maxSurge (rounded up) and maxUnavailable
+// (rounded down) against the desired replicas. Both cannot be zero,
+// hence maxUnavailable is 1 when they are.
+func (d *deployment) rollingLimits() (surge, unavailable int) {
+	surge = int(math.Ceil(d.maxSurge * float64(d.desiredReplicas)))
+	unavailable = int(math.Floor(d.maxUnavailable * float64(d.desiredReplicas)))
+	if surge == 0 && unavailable == 0 {
+		unavailable = 1
+	}
+	return surge, unavailable
+}
+
+// setReplicas scales the ReplicaSet, emitting an event like the deployment controller.
+func (d *deployment) setReplicas(rs *replicaSet, replicas int, now time.Time) {
+	if replicas == rs.replicas {
+		return
+	}
+	verb := "up"
+	if replicas < rs.replicas {
+		verb = "down"
+	}
+	rs.replicas = replicas
+	d.cluster.events.emit(now, "Normal", "ScalingReplicaSet", "deployment/"+d.name,
+		fmt.Sprintf("Scaled %s replica set %s to %d", verb, d.replicaSetName(rs.revision), replicas))
+}
+
+// initReplicaSets creates the first ReplicaSet generation.
+func (d *deployment) initReplicaSets() {
+	if len(d.replicaSets) == 0 {
+		d.revision = 1
+		d.replicaSets = []replicaSet{{revision: d.revision}}
+	}
+}
+
+// syncReplicaSets sets the replicas of the ReplicaSets like the deployment
+// controller: when the desired replicas change (e.g. by the HPA), every active
+// ReplicaSet is scaled proportionally, otherwise the rolling update takes a step.
+//
+// See: https://kubernetes.io/docs/concepts/workloads/controllers/deployment/#proportional-scaling
+func (d *deployment) syncReplicaSets(pods []pod, now time.Time) {
+	if d.desiredReplicas != d.scaledReplicas {
+		d.scaledReplicas = d.desiredReplicas
+		d.scaleProportionally(now)
+		return
+	}
+
+	d.rollingUpdate(pods, now)
+}
+
+// scaleProportionally spreads the replicas added or removed among
+// the active ReplicaSets by their share of the replicas. The leftover
+// goes to the newest ReplicaSet. With a single active ReplicaSet,
+// it takes the desired replicas.
+func (d *deployment) scaleProportionally(now time.Time) {
+	var active, total int
+	for _, rs := range d.replicaSets {
+		if rs.replicas > 0 {
+			active++
+			total += rs.replicas
+		}
+	}
+	if active <= 1 {
+		for i := range d.replicaSets {
+			rs := &d.replicaSets[i]
+			if rs.replicas > 0 || (active == 0 && rs == d.newest()) {
+				d.setReplicas(rs, d.desiredReplicas, now)
+			}
+		}
+		return
+	}
+
+	surge, _ := d.rollingLimits()
+	toAdd := d.desiredReplicas + surge - total
+	if toAdd == 0 {
+		return
+	}
+
+	added := make([]int, len(d.replicaSets))
+	var sum int
+	for i, rs := range d.replicaSets {
+		added[i] = rs.replicas * toAdd / total
+		sum += added[i]
+	}
+
+	// leftover to the newest ReplicaSet first, never below zero replicas
+	leftover := toAdd - sum
+	for i := len(added) - 1; i >= 0 && leftover != 0; i-- {
+		share := max(leftover, -(d.replicaSets[i].replicas + added[i]))
+		added[i] += share
+		leftover -= share
+	}
+
+	for i := range d.replicaSets {
+		d.setReplicas(&d.replicaSets[i], d.replicaSets[i].replicas+added[i], now)
+	}
+}
+
+// rollingUpdate scales up the newest ReplicaSet up to maxSurge pods above
+// the desired replicas, and scales down the old ReplicaSets as long as
+// no more than maxUnavailable pods are unavailable.
+func (d *deployment) rollingUpdate(pods []pod, now time.Time) {
+	newRS := d.newest()
+	surge, unavailable := d.rollingLimits()
+
+	// scale up the new ReplicaSet
+	switch {
+	case newRS.replicas > d.desiredReplicas:
+		d.setReplicas(newRS, d.desiredReplicas, now)
+	case newRS.replicas < d.desiredReplicas:
+		up := min(d.desiredReplicas+surge-d.totalReplicas(), d.desiredReplicas-newRS.replicas)
+		if up > 0 {
+			d.setReplicas(newRS, newRS.replicas+up, now)
+		}
+	}
+
+	if d.totalReplicas() == newRS.replicas {
+		return // no old replicas
+	}
+
+	// scale down the old ReplicaSets: unhealthy pods first, then available pods
+	minAvailable := d.desiredReplicas - unavailable
+	newUnavailable := newRS.replicas - countAvailable(pods, newRS.revision)
+	maxScaledDown := d.totalReplicas() - minAvailable - newUnavailable
+	for i := range d.replicaSets[:len(d.replicaSets)-1] {
+		rs := &d.replicaSets[i]
+		unhealthy := max(rs.replicas-countAvailable(pods, rs.revision), 0)
+		down := min(unhealthy, maxScaledDown)
+		if down > 0 {
+			d.setReplicas(rs, rs.replicas-down, now)
+			maxScaledDown -= down
+		}
+	}
+
+	toScaleDown := countAvailable(pods, 0) - minAvailable
+	for i := range d.replicaSets[:len(d.replicaSets)-1] {
+		rs := &d.replicaSets[i]
+		down := min(rs.replicas, toScaleDown)
+		if down > 0 {
+			d.setReplicas(rs, rs.replicas-down, now)
+			toScaleDown -= down
+		}
+	}
+}
+
+// totalReplicas returns the replicas of all ReplicaSets.
+func (d *deployment) totalReplicas() int {
+	var total int
+	for _, rs := range d.replicaSets {
+		total += rs.replicas
+	}
+	return total
+}
+
+// countAvailable returns the ready pods of the revision, of any revision if zero.
+func countAvailable(pods []pod, revision int) int {
+	var count int
+	for _, p := range pods {
+		if p.status == podStatusReady && (revision == 0 || p.revision == revision) {
+			count++
+		}
+	}
+	return count
+}
+
+// removeOldReplicaSets forgets the old ReplicaSets scaled to zero with no pods left,
+// and reports the rollout completion.
+func (d *deployment) removeOldReplicaSets(pods []pod, now time.Time) {
+	newest := d.newest().revision
+	d.replicaSets = slices.DeleteFunc(d.replicaSets, func(rs replicaSet) bool {
+		return rs.revision != newest && rs.replicas == 0 &&
+			!slices.ContainsFunc(pods, func(p pod) bool { return p.revision == rs.revision })
+	})
+	if len(d.replicaSets) == 1 && !d.rolloutStart.IsZero() {
+		d.rolloutDuration = now.Sub(d.rolloutStart)
+		d.rolloutStart = time.Time{}
+		d.cluster.events.emit(now, "Normal", "RolloutComplete", d.object(),
+			fmt.Sprintf("%s has successfully progressed in %v", d.kindName(), d.rolloutDuration.Round(time.Second)))
+	}
+}
+
+// updateRolloutLegend shows the newest revision, new and old pods, and the rollout time.
+func updateRolloutLegend(legend js.Value, d *deployment, now time.Time) {
+	newRS := d.newest()
+	var oldPods int
+	for _, p := range d.podList {
+		if p.revision != newRS.revision && p.status != podStatusFailed {
+			oldPods++
+		}
+	}
+	status := "complete"
+	if d.rolloutDuration > 0 {
+		status = fmt.Sprintf("complete in %v", d.rolloutDuration.Round(time.Second))
+	}
+	if !d.rolloutStart.IsZero() {
+		status = fmt.Sprintf("progressing for %v", now.Sub(d.rolloutStart).Round(time.Second))
+	}
+	legend.Call("querySelector", ".rollout-revision").Set("innerText",
+		d.replicaSetName(newRS.revision))
+	legend.Call("querySelector", ".rollout-new").Set("innerText",
+		fmt.Sprintf("%d/%d", countAvailable(d.podList, newRS.revision), newRS.replicas))
+	legend.Call("querySelector", ".rollout-old").Set("innerText",
+		fmt.Sprintf("%d", oldPods))
+	legend.Call("querySelector", ".rollout-status").Set("innerText", status)
+}
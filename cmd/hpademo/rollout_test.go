@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// rolloutDeployment returns a deployment in the middle of a rollout,
+// with ReplicaSets of the given replicas, oldest first.
+func rolloutDeployment(desired int, maxSurge float64, replicas ...int) *deployment {
+	d := &deployment{name: "workload", cluster: &cluster{}, desiredReplicas: desired, rolloutState: rolloutState{maxSurge: maxSurge}}
+	for i, r := range replicas {
+		d.replicaSets = append(d.replicaSets, replicaSet{revision: i + 1, replicas: r})
+	}
+	return d
+}
+
+func TestScaleProportionallyKeepsTotalAndShares(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		replicas := make([]int, 2+rng.IntN(3))
+		for i := range replicas {
+			replicas[i] = 1 + rng.IntN(20)
+		}
+		desired := 1 + rng.IntN(60)
+		maxSurge := float64(rng.IntN(3)) / 4
+		d := rolloutDeployment(desired, maxSurge, replicas...)
+		surge, _ := d.rollingLimits()
+
+		d.scaleProportionally(testNow)
+
+		var total int
+		for i, rs := range d.replicaSets {
+			if rs.replicas < 0 {
+				t.Fatalf("%v to %d: ReplicaSet %d scaled to %d", replicas, desired, i, rs.replicas)
+			}
+			total += rs.replicas
+		}
+		if total != desired+surge {
+			t.Fatalf("%v to %d (surge %d): total %d, want %d", replicas, desired, surge, total, desired+surge)
+		}
+		// scaling up never shrinks a ReplicaSet, scaling down never grows one
+		up := desired+surge > sum(replicas)
+		for i, rs := range d.replicaSets {
+			if (up && rs.replicas < replicas[i]) || (!up && rs.replicas > replicas[i]) {
+				t.Fatalf("%v to %d: ReplicaSet %d moved against the scaling to %d", replicas, desired, i, rs.replicas)
+			}
+		}
+	}
+}
+
+func TestScaleProportionallyLeftoverToNewest(t *testing.T) {
+	d := rolloutDeployment(4, 0, 2, 1) // one replica to add, no whole share for either
+	d.scaleProportionally(testNow)
+	if old, newest := d.replicaSets[0].replicas, d.replicaSets[1].replicas; old != 2 || newest != 2 {
+		t.Errorf("replicas old=%d newest=%d, want the leftover on the newest: 2 and 2", old, newest)
+	}
+}
+
+func TestScaleProportionallySingleReplicaSet(t *testing.T) {
+	d := rolloutDeployment(7, 0.25, 0, 3)
+	d.scaleProportionally(testNow)
+	if got := d.replicaSets[1].replicas; got != 7 {
+		t.Errorf("only active ReplicaSet scaled to %d, want the desired 7 without surge", got)
+	}
+
+	d = rolloutDeployment(5, 0, 0, 0)
+	d.scaleProportionally(testNow)
+	if d.replicaSets[0].replicas != 0 || d.replicaSets[1].replicas != 5 {
+		t.Errorf("no active ReplicaSet: replicas %v, want the newest to take 5", d.replicaSets)
+	}
+}
+
+func sum(values []int) int {
+	var total int
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
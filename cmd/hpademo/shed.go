@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"syscall/js"
+)
+
+type sheddingPolicy string
+
+const (
+	sheddingReject sheddingPolicy = "reject" // reject everything beyond capacity
+	sheddingQueue  sheddingPolicy = "queue"  // queue up to queueSize, then reject
+)
+
+// loadShedder decides what happens to the load the pods could not serve.
+//
+// With sheddingReject, unserved load is rejected immediately.
+// With sheddingQueue, up to queueSize mCores of unserved load wait
+// for the next seconds, when they are offered again ahead of new load,
+// oldest first. Load waiting longer than timeout seconds times out.
+// Whatever times out or does not fit the queue is rejected.
+type loadShedder struct {
+	policy    sheddingPolicy
+	queueSize float64
+	timeout   int          // seconds load may wait in the queue
+	queue     []queuedLoad // oldest first
+}
+
+// queuedLoad is load that arrived in the same second, waiting in the queue.
+type queuedLoad struct {
+	load float64
+	age  int // seconds waited
+}
+
+// queued returns the load waiting in the queue.
+func (s *loadShedder) queued() float64 {
+	var sum float64
+	for _, q := range s.queue {
+		sum += q.load
+	}
+	return sum
+}
+
+// offer returns the load offered to the pods in this second:
+// the new demand plus the load waiting in the queue.
+func (s *loadShedder) offer(demand float64) float64 {
+	if s.policy != sheddingQueue {
+		s.queue = nil
+	}
+	return demand + s.queued()
+}
+
+// shed accounts for the load the pods did not serve this second, given
+// the new demand and the load served, queued load first. Unserved load
+// is queued or rejected according to the shedding policy. It returns
+// the load rejected, whether now or after timing out in the queue, and
+// the new demand not served this second.
+func (s *loadShedder) shed(demand, served float64) (rejected, unmet float64) {
+	var waiting []queuedLoad
+	for _, q := range s.queue {
+		taken := min(q.load, served)
+		served -= taken
+		if q.load -= taken; q.load > 0 {
+			waiting = append(waiting, q)
+		}
+	}
+	unmet = max(demand-served, 0)
+
+	if s.policy != sheddingQueue {
+		s.queue = nil
+		return unmet, unmet
+	}
+	if unmet > 0 {
+		waiting = append(waiting, queuedLoad{load: unmet})
+	}
+
+	s.queue = nil
+	room := s.queueSize
+	for _, q := range waiting {
+		q.age++
+		if q.age > s.timeout {
+			rejected += q.load // timed out
+			continue
+		}
+		kept := min(q.load, room)
+		room -= kept
+		rejected += q.load - kept // queue full
+		if kept > 0 {
+			s.queue = append(s.queue, queuedLoad{load: kept, age: q.age})
+		}
+	}
+	return rejected, unmet
+}
+
+// errorRate returns the percentage of offered load that was rejected.
+// Load queued in earlier seconds may time out now, hence the rate is
+// capped at 100%.
+func errorRate(offered, rejected float64) float64 {
+	if offered <= 0 {
+		return 0
+	}
+	return min(100*rejected/offered, 100)
+}
+
+// availability keeps per-second offered and rejected load
+// over the chart history window in order to compute an availability SLI.
+type availability struct {
+	offered  []float64
+	rejected []float64
+}
+
+func newAvailability(historySize int) availability {
+	return availability{
+		offered:  make([]float64, historySize),
+		rejected: make([]float64, historySize),
+	}
+}
+
+func (a *availability) record(offered, rejected float64) {
+	last := len(a.offered) - 1
+	copy(a.offered, a.offered[1:])
+	copy(a.rejected, a.rejected[1:])
+	a.offered[last] = offered
+	a.rejected[last] = rejected
+}
+
+func (a *availability) resize(newSize int) {
+	a.offered = resizeSliceFloat(a.offered, newSize)
+	a.rejected = resizeSliceFloat(a.rejected, newSize)
+}
+
+func resizeSliceFloat(oldSlice []float64, newSize int) []float64 {
+	if newSize == len(oldSlice) {
+		// no change
+		return oldSlice
+	}
+
+	newSlice := make([]float64, newSize)
+
+	// copy existing data to new slice
+	copySize := min(len(oldSlice), newSize)
+
+	copy(newSlice[newSize-copySize:], oldSlice[len(oldSlice)-copySize:])
+
+	return newSlice
+}
+
+// sliSummary summarizes the error budget burn over the history window.
+type sliSummary struct {
+	availability   float64 // percentage of offered load that was served
+	errorSeconds   int     // seconds with any rejected load
+	longestOutage  int     // longest run of consecutive seconds with errors
+	peakErrorRate  float64 // worst per-second error rate
+	lastOutageRate float64 // average error rate during the latest run of errors
+	lastOutageSecs int     // duration of the latest run of errors
+}
+
+func (a *availability) summary() sliSummary {
+	var s sliSummary
+	var totalOffered, totalRejected float64
+	var run int
+	var runRate float64
+
+	for i, offered := range a.offered {
+		rejected := a.rejected[i]
+		totalOffered += offered
+		totalRejected += rejected
+
+		if rejected <= 0 {
+			run = 0
+			runRate = 0
+			continue
+		}
+
+		rate := errorRate(offered, rejected)
+		s.errorSeconds++
+		s.peakErrorRate = max(s.peakErrorRate, rate)
+		run++
+		runRate += rate
+		s.longestOutage = max(s.longestOutage, run)
+		s.lastOutageSecs = run
+		s.lastOutageRate = runRate / float64(run)
+	}
+
+	s.availability = 100 - errorRate(totalOffered, totalRejected)
+
+	return s
+}
+
+// String renders the summary the way product owners talk about errors:
+// "3% errors for 90 seconds".
+func (s sliSummary) String() string {
+	if s.lastOutageSecs == 0 {
+		return "no errors"
+	}
+	return fmt.Sprintf("%.1f%% errors for %d seconds",
+		s.lastOutageRate, s.lastOutageSecs)
+}
+
+// updateSLILegend shows the availability, error seconds, outages and peak error rate.
+func updateSLILegend(legend js.Value, s sliSummary) {
+	legend.Call("querySelector", ".sli-availability").Set("innerText",
+		fmt.Sprintf("%.3f%%", math.Floor(s.availability*1000)/1000))
+	legend.Call("querySelector", ".sli-error-seconds").Set("innerText",
+		fmt.Sprintf("%ds", s.errorSeconds))
+	legend.Call("querySelector", ".sli-longest-outage").Set("innerText",
+		fmt.Sprintf("%ds", s.longestOutage))
+	legend.Call("querySelector", ".sli-peak-error-rate").Set("innerText",
+		fmt.Sprintf("%.1f%%", s.peakErrorRate))
+	legend.Call("querySelector", ".sli-last-outage").Set("innerText", s.String())
+}
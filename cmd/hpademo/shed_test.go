@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+func TestShedRejectPolicy(t *testing.T) {
+	s := loadShedder{policy: sheddingReject, queueSize: 1000, timeout: 5}
+	if offered := s.offer(100); offered != 100 {
+		t.Fatalf("offered %v, want the demand 100", offered)
+	}
+	rejected, unmet := s.shed(100, 50)
+	if rejected != 50 || unmet != 50 {
+		t.Errorf("rejected=%v unmet=%v, want 50 and 50", rejected, unmet)
+	}
+	if s.queued() != 0 {
+		t.Errorf("queued %v under the reject policy", s.queued())
+	}
+}
+
+func TestShedQueueServesOldestFirst(t *testing.T) {
+	s := loadShedder{policy: sheddingQueue, queueSize: 1000, timeout: 5}
+
+	s.offer(100)
+	if rejected, _ := s.shed(100, 50); rejected != 0 {
+		t.Fatalf("rejected %v with room in the queue", rejected)
+	}
+	if offered := s.offer(100); offered != 150 {
+		t.Fatalf("offered %v, want the new demand plus the 50 queued", offered)
+	}
+
+	// 80 served: the 50 queued first, then 30 of the new demand
+	rejected, unmet := s.shed(100, 80)
+	if rejected != 0 || unmet != 70 {
+		t.Errorf("rejected=%v unmet=%v, want 0 and 70", rejected, unmet)
+	}
+	if s.queued() != 70 || len(s.queue) != 1 || s.queue[0].age != 1 {
+		t.Errorf("queue %+v, want only the 70 of this second", s.queue)
+	}
+}
+
+func TestShedQueueOverflow(t *testing.T) {
+	s := loadShedder{policy: sheddingQueue, queueSize: 30, timeout: 5}
+	s.offer(100)
+	rejected, unmet := s.shed(100, 50)
+	if unmet != 50 || rejected != 20 || s.queued() != 30 {
+		t.Errorf("unmet=%v rejected=%v queued=%v, want 50, 20 and a full queue of 30",
+			unmet, rejected, s.queued())
+	}
+}
+
+func TestShedQueueTimeout(t *testing.T) {
+	s := loadShedder{policy: sheddingQueue, queueSize: 1000, timeout: 2}
+	s.offer(50)
+	s.shed(50, 0) // 50 waits, 1s old
+
+	for second := 2; second <= 3; second++ {
+		s.offer(0)
+		rejected, _ := s.shed(0, 0)
+		switch {
+		case second <= s.timeout && rejected != 0:
+			t.Errorf("second %d: rejected %v before the timeout", second, rejected)
+		case second > s.timeout && rejected != 50:
+			t.Errorf("second %d: rejected %v, want the 50 timed out", second, rejected)
+		}
+	}
+	if s.queued() != 0 {
+		t.Errorf("queued %v after the timeout", s.queued())
+	}
+}
+
+func TestErrorRateCapped(t *testing.T) {
+	// load queued in earlier seconds may time out along with the new demand
+	if rate := errorRate(10, 50); rate != 100 {
+		t.Errorf("error rate %v, want capped at 100", rate)
+	}
+	if rate := errorRate(0, 50); rate != 0 {
+		t.Errorf("error rate %v without demand, want 0", rate)
+	}
+	if rate := errorRate(200, 50); rate != 25 {
+		t.Errorf("error rate %v, want 25", rate)
+	}
+}
+
+func TestAvailabilitySummary(t *testing.T) {
+	a := newAvailability(8)
+	if got := a.summary().String(); got != "no errors" {
+		t.Errorf("String() = %q without errors", got)
+	}
+	for _, r := range []float64{0, 10, 30, 0, 0, 50, 50, 0} {
+		a.record(100, r)
+	}
+	a.record(100, 0) // drops the oldest second
+
+	s := a.summary()
+	if s.errorSeconds != 4 || s.longestOutage != 2 || s.peakErrorRate != 50 {
+		t.Errorf("summary %+v, want 4 error seconds, longest outage 2 and peak 50%%", s)
+	}
+	if s.availability != 100-140.0/8 {
+		t.Errorf("availability %v, want %v", s.availability, 100-140.0/8)
+	}
+	if got, want := s.String(), "50.0% errors for 2 seconds"; got != want {
+		t.Errorf("String() = %q, want the latest outage %q", got, want)
+	}
+
+	a.record(100, 20)
+	a.record(100, 40)
+	if got, want := a.summary().String(), "30.0% errors for 2 seconds"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
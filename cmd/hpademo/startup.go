@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+type startupDistribution string
+
+const (
+	startupFixed     startupDistribution = "fixed"      // every pod takes the base time
+	startupUniform   startupDistribution = "uniform"    // uniform between base*(1-spread) and base*(1+spread)
+	startupNormal    startupDistribution = "normal"     // mean base, stddev base*spread
+	startupLogNormal startupDistribution = "log-normal" // median base, sigma spread: long tail
+)
+
+// sampleDuration draws a random duration around base according to the distribution.
+// spread is relative to base (0.5 = 50%), see startupDistribution.
+// The result is never negative.
+func sampleDuration(rng *rand.Rand, dist startupDistribution, base time.Duration, spread float64) time.Duration {
+	b := float64(base)
+	var d float64
+	switch dist {
+	case startupUniform:
+		d = b * (1 - spread + 2*spread*rng.Float64())
+	case startupNormal:
+		d = b + b*spread*rng.NormFloat64()
+	case startupLogNormal:
+		d = b * math.Exp(spread*rng.NormFloat64())
+	default:
+		d = b
+	}
+	return time.Duration(max(d, 0))
+}
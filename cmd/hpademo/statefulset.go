@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// workloadKind is the kind of the scale target of a workload.
+type workloadKind string
+
+const (
+	kindDeployment  workloadKind = "Deployment"
+	kindStatefulSet workloadKind = "StatefulSet"
+)
+
+// podManagementPolicy is the podManagementPolicy of a StatefulSet.
+type podManagementPolicy string
+
+const (
+	podManagementOrderedReady podManagementPolicy = "OrderedReady" // one pod at a time, in ordinal order
+	podManagementParallel     podManagementPolicy = "Parallel"     // all pods at once
+)
+
+// kindName returns the kind of the scale target, a Deployment unless set.
+func (d *deployment) kindName() string {
+	if d.kind == kindStatefulSet {
+		return string(kindStatefulSet)
+	}
+	return string(kindDeployment)
+}
+
+// object returns the scale target as shown in events, e.g. deployment/workload-1.
+func (d *deployment) object() string {
+	return strings.ToLower(d.kindName()) + "/" + d.name
+}
+
+// statefulPodName returns the stable name of the StatefulSet pod.
+func (d *deployment) statefulPodName(ordinal int) string {
+	return fmt.Sprintf("%s-%d", d.name, ordinal)
+}
+
+// reconcileStatefulSet brings the pods to the desired replicas like the
+// StatefulSet controller: the pod with ordinal i has the stable identity
+// name-i, and is only created again once its previous incarnation is gone.
+//
+// With the OrderedReady policy, pods are created one at a time in ordinal
+// order, each one waiting for its predecessors to be ready, and removed
+// one at a time from the highest ordinal down, each one waiting for its
+// successors to be gone. With the Parallel policy, pods are created and
+// removed all at once.
+//
+// Once every pod is ready, a rolling update replaces the pods of older
+// revisions one at a time, from the highest ordinal down.
+// It returns the updated pods.
+//
+// See: https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/#deployment-and-scaling-guarantees
+func (d *deployment) reconcileStatefulSet(pods []pod, now time.Time) []pod {
+	byOrdinal := map[int]int{} // index into pods
+	for i, p := range pods {
+		if p.ordinal >= 0 {
+			byOrdinal[p.ordinal] = i
+		}
+	}
+
+	pods, allReady, wait := d.createStatefulPods(pods, byOrdinal, now)
+	if wait {
+		return pods
+	}
+
+	if d.deleteCondemned(pods, byOrdinal, now) || !allReady {
+		return pods
+	}
+
+	// rolling update, from the highest ordinal down
+	for ordinal := d.desiredReplicas - 1; ordinal >= 0; ordinal-- {
+		p := &pods[byOrdinal[ordinal]]
+		if p.revision != d.revision {
+			deletePod(p, now)
+			return pods
+		}
+	}
+
+	return pods
+}
+
+// createStatefulPods creates the missing pods, in ordinal order.
+// It returns the updated pods, whether all of them are ready, and whether
+// the OrderedReady policy must wait for a predecessor before going on.
+func (d *deployment) createStatefulPods(pods []pod, byOrdinal map[int]int, now time.Time) ([]pod, bool, bool) {
+	ordered := d.podManagement == podManagementOrderedReady
+	allReady := true
+	for ordinal := range d.desiredReplicas {
+		if i, found := byOrdinal[ordinal]; found {
+			if pods[i].status == podStatusReady {
+				continue
+			}
+			allReady = false
+			if ordered {
+				return pods, false, true // wait for the predecessor to be ready
+			}
+			continue
+		}
+		allReady = false
+		cpuRequest, _, cpuLimit := d.podRequests()
+		if err := d.quota.admit(workloadQuotaUsed(pods), cpuRequest, cpuLimit); err != nil {
+			// the StatefulSet controller retries on its next sync
+			d.failedCreates++
+			d.cluster.events.emit(now, "Warning", "FailedCreate", d.object(),
+				fmt.Sprintf("create Pod %s in StatefulSet %s failed error: pods %q is forbidden: %v",
+					d.statefulPodName(ordinal), d.name, d.statefulPodName(ordinal), err))
+			return pods, false, true
+		}
+		p := d.newPod(now)
+		p.revision = d.revision
+		p.ordinal = ordinal
+		pods = append(pods, p)
+		if ordered {
+			return pods, false, true
+		}
+	}
+	return pods, allReady, false
+}
+
+// deleteCondemned deletes the pods beyond the desired replicas, from the
+// highest ordinal down, one at a time with the OrderedReady policy.
+// It returns true if any pod is condemned.
+func (d *deployment) deleteCondemned(pods []pod, byOrdinal map[int]int, now time.Time) bool {
+	var condemned []int
+	for ordinal := range byOrdinal {
+		if ordinal >= d.desiredReplicas {
+			condemned = append(condemned, ordinal)
+		}
+	}
+	slices.Sort(condemned)
+	slices.Reverse(condemned)
+	for _, ordinal := range condemned {
+		deletePod(&pods[byOrdinal[ordinal]], now)
+		if d.podManagement == podManagementOrderedReady {
+			break // wait for it to be gone
+		}
+	}
+	return len(condemned) > 0
+}
+
+// deletePod moves the pod to the terminating path,
+// unless it is already on its way out.
+func deletePod(p *pod, now time.Time) {
+	switch p.status {
+	case podStatusTerminating, podStatusFailed:
+		return
+	}
+	p.setStatus(podStatusTerminating, now)
+}
+
+// syncRevisions sets the replicas of every revision of the StatefulSet
+// to its pods, and reports the rolling update completion.
+func (d *deployment) syncRevisions(pods []pod, now time.Time) {
+	for i := range d.replicaSets {
+		rs := &d.replicaSets[i]
+		rs.replicas = 0
+		for _, p := range pods {
+			if p.ordinal >= 0 && p.revision == rs.revision && p.status != podStatusFailed {
+				rs.replicas++
+			}
+		}
+	}
+	d.removeOldReplicaSets(pods, now)
+}
+
+// removeOrphans deletes the pods owned by the previous kind of scale target,
+// as when a Deployment is replaced with a StatefulSet or the other way around.
+// StatefulSet pods have an ordinal, Deployment pods do not.
+func (d *deployment) removeOrphans(pods []pod, now time.Time) {
+	for i, p := range pods {
+		if (p.ordinal >= 0) == (d.kind == kindStatefulSet) {
+			continue
+		}
+		deletePod(&pods[i], now)
+	}
+}
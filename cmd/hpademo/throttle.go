@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+	"time"
+)
+
+// qosClass is the Quality of Service class Kubernetes gives a pod
+// from its requests and limits.
+//
+// See: https://kubernetes.io/docs/concepts/workloads/pods/pod-qos/
+type qosClass string
+
+const (
+	qosGuaranteed qosClass = "Guaranteed" // limits equal to requests
+	qosBurstable  qosClass = "Burstable"  // some request, limits above requests or unset
+	qosBestEffort qosClass = "BestEffort" // no requests nor limits
+)
+
+// podQOS returns the QoS class of a pod with the requests and limits.
+func podQOS(cpuRequest, memoryRequest, cpuLimit, memoryLimit float64) qosClass {
+	switch {
+	case cpuRequest <= 0 && memoryRequest <= 0 && cpuLimit <= 0 && memoryLimit <= 0:
+		return qosBestEffort
+	case cpuLimit > 0 && cpuLimit == cpuRequest && memoryLimit > 0 && memoryLimit == memoryRequest:
+		return qosGuaranteed
+	}
+	return qosBurstable
+}
+
+// qos returns the QoS class of the pod. The memory limit is the
+// template one, as it is not resized in place.
+func (d *deployment) qos(p pod) qosClass {
+	return podQOS(p.cpuRequest, p.memoryRequest, p.cpuLimit, d.memoryLimit)
+}
+
+// qosPreset is the QoS class option of the workload: how the request and
+// limit sliders make up the pod template.
+type qosPreset string
+
+const (
+	presetBurstable  qosPreset = "Burstable"          // requests and limits as set
+	presetUnlimited  qosPreset = "Burstable-no-limit" // no CPU limit, bursts into spare node CPU
+	presetGuaranteed qosPreset = "Guaranteed"         // limits set to the requests
+	presetBestEffort qosPreset = "BestEffort"         // requests and limits removed
+)
+
+const (
+	cfsPeriod         = 100 * time.Millisecond
+	cfsPeriodsPerTick = 10 // CFS periods in the one second step of the simulation
+)
+
+// applyQoS adjusts the pod template requests and limits to the preset.
+// Guaranteed pods get the CPU limit of their request, and the memory
+// request of their limit (or the limit of their request, if unset).
+func (d *deployment) applyQoS(preset qosPreset) {
+	switch preset {
+	case presetUnlimited:
+		d.cpuLimit = 0
+	case presetGuaranteed:
+		d.cpuLimit = d.cpuRequest
+		if d.memoryLimit > 0 {
+			d.memoryRequest = d.memoryLimit
+		} else {
+			d.memoryLimit = d.memoryRequest
+		}
+	case presetBestEffort:
+		d.cpuRequest, d.memoryRequest, d.cpuLimit, d.memoryLimit = 0, 0, 0, 0
+	}
+}
+
+// cpuCeiling returns the most CPU the pod container can use: its CPU
+// limit, enforced as a CFS quota, or, without a limit, its request plus
+// its share of the node CPU no pod requested, split among the pods on
+// the node without a limit.
+func (c *cluster) cpuCeiling(p pod) float64 {
+	if p.cpuLimit > 0 {
+		return p.cpuLimit
+	}
+	n := c.findNode(p.node)
+	if n == nil {
+		return p.cpuRequest
+	}
+	return p.cpuRequest + max(n.cpu-n.cpuRequested, 0)/float64(max(n.unlimited, 1))
+}
+
+// cfsThrottle returns the CPU the container gets when asked for demand
+// mCores on average over the second, with its CPU limit enforced by the
+// CFS quota: limit mCores per cfsPeriod. Bursty load asks for more than
+// the average in some periods, spread evenly by burstiness (0.3 = ±30%)
+// around it. In the periods asking for more than the quota the container
+// is throttled until the next period. The work left over adds latency
+// and is lost to the second, reducing the throughput below the limit.
+// It also returns the fraction of the periods throttled and the average
+// delay added to a request.
+func cfsThrottle(demand, limit, burstiness float64) (used, throttled float64, delay time.Duration) {
+	if demand <= 0 || limit <= 0 {
+		return demand, 0, 0
+	}
+	var excess float64
+	for i := range cfsPeriodsPerTick {
+		offset := (2*(float64(i)+0.5)/cfsPeriodsPerTick - 1) * burstiness
+		asked := demand * (1 + offset)
+		if asked > limit {
+			throttled++
+			excess += asked - limit
+		}
+	}
+	excess /= cfsPeriodsPerTick
+	throttled /= cfsPeriodsPerTick
+	// the work over the quota waits on average half a period
+	delay = time.Duration(excess / demand * float64(cfsPeriod) / 2)
+	return demand - excess, throttled, delay
+}
+
+// throttleStats returns, among the ready pods of the deployment, the
+// average percent of CFS periods throttled, the average CPU usage in
+// percent of the CPU request (0 if the pods request no CPU) and the
+// average delay throttling added to a request, weighted by load.
+func (d *deployment) throttleStats() (throttled, usageOfRequest float64, delay time.Duration) {
+	var pods int
+	var usage, request, load, weighted float64
+	for _, p := range d.podList {
+		if p.status != podStatusReady {
+			continue
+		}
+		pods++
+		throttled += p.throttled
+		usage += p.cpuUsage
+		request += p.cpuRequest
+		load += p.load
+		weighted += p.load * float64(p.throttleDelay)
+	}
+	if pods == 0 {
+		return 0, 0, 0
+	}
+	if request > 0 {
+		usageOfRequest = 100 * usage / request
+	}
+	if load > 0 {
+		delay = time.Duration(weighted / load)
+	}
+	return 100 * throttled / float64(pods), usageOfRequest, delay
+}
+
+// updateThrottlingLegend shows the QoS class, the CFS throttling latency and the CPU usage of the request.
+func updateThrottlingLegend(legend js.Value, d *deployment) {
+	class := podQOS(d.cpuRequest, d.memoryRequest, d.cpuLimit, d.memoryLimit)
+	limit := "no CPU limit: bursts into spare node CPU, never throttled"
+	if d.cpuLimit > 0 {
+		limit = fmt.Sprintf("CFS quota %dm per %v period", int(d.cpuLimit), cfsPeriod)
+	}
+	legend.Call("querySelector", ".qos-class").Set("innerText", fmt.Sprintf("%s, %s", class, limit))
+
+	throttled, usageOfRequest, delay := d.throttleStats()
+	legend.Call("querySelector", ".throttle-latency").Set("innerText",
+		fmt.Sprintf("+%v per request, %.0f%% of periods throttled", delay.Round(time.Millisecond), throttled))
+
+	var text string
+	switch {
+	case d.cpuRequest <= 0:
+		text = "no CPU request: the HPA cannot compute utilization"
+	case usageOfRequest > 100:
+		text = fmt.Sprintf("%.0f%% of request: pods use CPU past the request (reserved by the scheduler, divided by the HPA) up to the limit or spare node CPU",
+			usageOfRequest)
+	default:
+		text = fmt.Sprintf("%.0f%% of request: within the CPU reserved by the scheduler", usageOfRequest)
+	}
+	legend.Call("querySelector", ".usage-vs-request").Set("innerText", text)
+}
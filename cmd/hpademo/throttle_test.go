@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCFSThrottleWithoutLimit(t *testing.T) {
+	used, throttled, delay := cfsThrottle(5000, 0, 0.5)
+	if used != 5000 || throttled != 0 || delay != 0 {
+		t.Errorf("no limit: used=%v throttled=%v delay=%v, want all the demand, never throttled", used, throttled, delay)
+	}
+}
+
+func TestCFSThrottleSteadyLoad(t *testing.T) {
+	// without bursts, every period asks for the average
+	if used, throttled, _ := cfsThrottle(400, 500, 0); used != 400 || throttled != 0 {
+		t.Errorf("below the limit: used=%v throttled=%v, want 400 and 0", used, throttled)
+	}
+	used, throttled, delay := cfsThrottle(600, 500, 0)
+	if used != 500 || throttled != 1 {
+		t.Errorf("above the limit: used=%v throttled=%v, want the limit in every period", used, throttled)
+	}
+	// 100 of 600 waits half a period on average
+	if want := cfsPeriod / 12; (delay - want).Abs() > time.Microsecond {
+		t.Errorf("delay %v, want %v", delay, want)
+	}
+}
+
+func TestCFSThrottleBursts(t *testing.T) {
+	// ±45% bursts around 400m: the 10 periods ask for 220m, 260m ... 580m,
+	// the last two over the 500m quota, 40m and 80m over it
+	used, throttled, delay := cfsThrottle(400, 500, 0.5)
+	if !near(throttled, 0.2) {
+		t.Errorf("throttled %v, want 2 of 10 periods", throttled)
+	}
+	if !near(used, 400-12) {
+		t.Errorf("used %v, want the 12m average over the quota lost", used)
+	}
+	// 12 of 400 waits half a period on average
+	if want := 1500 * time.Microsecond; (delay - want).Abs() > time.Microsecond {
+		t.Errorf("delay %v, want %v", delay, want)
+	}
+
+	// less bursty, less throttled: only the 508m period
+	if _, calmer, _ := cfsThrottle(400, 500, 0.3); !near(calmer, 0.1) {
+		t.Errorf("throttled %v at ±27%%, want 1 of 10 periods", calmer)
+	}
+
+	// bursts stay under a limit high enough
+	if _, throttled, _ := cfsThrottle(400, 600, 0.5); throttled != 0 {
+		t.Errorf("throttled %v with the peak 580m under a 600m limit", throttled)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
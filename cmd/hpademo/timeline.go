@@ -0,0 +1,130 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"syscall/js"
+	"time"
+)
+
+// timelineRows is the maximum number of pods shown in the readiness timeline.
+const timelineRows = 40
+
+// timelinePods returns the most recent pods, deleted or not, that existed
+// during the time window ending now, ordered by pod id.
+func (d *deployment) timelinePods(now time.Time, window time.Duration) []pod {
+	start := now.Add(-window)
+
+	var list []pod
+	for _, p := range d.deleted {
+		if p.deletedAt.After(start) {
+			list = append(list, p)
+		}
+	}
+	list = append(list, d.podList...)
+
+	slices.SortFunc(list, func(a, b pod) int {
+		return cmp.Compare(a.id, b.id)
+	})
+
+	if len(list) > timelineRows {
+		list = list[len(list)-timelineRows:]
+	}
+
+	return list
+}
+
+// podSegment is a period of time a pod spent in one status.
+type podSegment struct {
+	status     podStatus
+	start, end time.Time
+}
+
+// segments returns the periods the pod spent in each status, in time order.
+func (p pod) segments(now time.Time) []podSegment {
+	list := make([]podSegment, 0, len(p.transitions))
+	for _, t := range p.transitions {
+		list = append(list, podSegment{status: t.status, start: t.at})
+	}
+
+	end := now
+	if !p.deletedAt.IsZero() {
+		end = p.deletedAt
+	}
+	for i := range list {
+		if i+1 < len(list) {
+			list[i].end = list[i+1].start
+		} else {
+			list[i].end = end
+		}
+	}
+
+	return list
+}
+
+// drawTimeline draws one row per pod, with the pod status over time,
+// for the time window ending now.
+func drawTimeline(ctx js.Value, width, height int, pods []pod, now time.Time, window time.Duration) {
+	ctx.Set("fillStyle", "white")
+	ctx.Call("fillRect", 0, 0, width, height)
+
+	if len(pods) == 0 || window <= 0 {
+		return
+	}
+
+	start := now.Add(-window)
+
+	// map time to canvas x
+	timeToX := func(t time.Time) int {
+		x := int(int64(t.Sub(start)) * int64(width) / int64(window))
+		return min(max(x, 0), width)
+	}
+
+	rowHeight := height / timelineRows
+
+	for row, p := range pods {
+		y := row * rowHeight
+		for _, seg := range p.segments(now) {
+			x1 := timeToX(seg.start)
+			x2 := timeToX(seg.end)
+			if x2 <= x1 {
+				continue
+			}
+			ctx.Set("fillStyle", statusColor(seg.status))
+			ctx.Call("fillRect", x1, y+1, x2-x1, rowHeight-2)
+		}
+
+		// pod id at the left side
+		ctx.Set("font", fmt.Sprintf("%dpx Arial", rowHeight-1))
+		ctx.Set("fillStyle", "black")
+		ctx.Call("fillText", fmt.Sprintf("%d", p.id), 2, y+rowHeight-2)
+	}
+}
+
+// startupLatency returns the median, 90th percentile and max time
+// taken by pods to go from creation to ready.
+func startupLatency(pods []pod) (p50, p90, maxLatency time.Duration) {
+	var list []time.Duration
+	for _, p := range pods {
+		if !p.firstReady.IsZero() {
+			list = append(list, p.firstReady.Sub(p.created))
+		}
+	}
+	if len(list) == 0 {
+		return 0, 0, 0
+	}
+	slices.Sort(list)
+	percentile := func(p int) time.Duration {
+		return list[(len(list)-1)*p/100]
+	}
+	return percentile(50), percentile(90), list[len(list)-1]
+}
+
+// updateTimelineLegend shows the p50, p90 and max time from creation to ready.
+func updateTimelineLegend(legend js.Value, pods []pod) {
+	p50, p90, maxLatency := startupLatency(pods)
+	legend.Call("querySelector", ".timeline-p50").Set("innerText", p50.Round(time.Second).String())
+	legend.Call("querySelector", ".timeline-p90").Set("innerText", p90.Round(time.Second).String())
+	legend.Call("querySelector", ".timeline-max").Set("innerText", maxLatency.Round(time.Second).String())
+}
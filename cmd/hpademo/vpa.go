@@ -0,0 +1,325 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"syscall/js"
+	"time"
+)
+
+// vpaMode is the updateMode of the VerticalPodAutoscaler of a deployment.
+type vpaMode string
+
+const (
+	vpaOff      vpaMode = "Off"               // recommend only
+	vpaInitial  vpaMode = "Initial"           // requests set on new pods only
+	vpaRecreate vpaMode = "Recreate"          // pods off the recommendation are evicted
+	vpaInPlace  vpaMode = "InPlaceOrRecreate" // pods resized in place, evicted if the node has no room
+)
+
+// vpaResources is the controlledResources of the VerticalPodAutoscaler.
+type vpaResources string
+
+const (
+	vpaCPUAndMemory vpaResources = "cpu-memory"
+	vpaMemoryOnly   vpaResources = "memory" // leaves CPU to the HPA
+)
+
+const (
+	vpaHalfLife       = 10 * time.Minute // usage samples weight half-life, 24h in the real recommender
+	vpaSafetyMargin   = 0.15             // added on top of the usage percentiles
+	vpaMinCPU         = 25               // mCores, lowest recommended request
+	vpaMinMemory      = 250              // MiB, lowest recommended request
+	vpaUpdateInterval = time.Minute      // updater loop period
+	vpaEvictTolerance = 0.5              // fraction of the replicas the updater may disrupt per loop
+	vpaMinReplicas    = 2                // running pods below which the updater leaves the pods alone
+)
+
+// histogram is a decaying histogram of usage samples with exponentially
+// growing buckets, like the VPA recommender keeps per container.
+type histogram struct {
+	weights []float64
+	total   float64
+}
+
+const (
+	histogramFirstBucket = 1.0  // mCores or MiB
+	histogramRatio       = 1.05 // each bucket 5% larger than the previous one
+	histogramBuckets     = 300  // up to about 2M
+)
+
+// bucketStart returns the lowest value of the bucket.
+func bucketStart(i int) float64 {
+	return histogramFirstBucket * math.Pow(histogramRatio, float64(i))
+}
+
+// add records a sample with weight 1.
+func (h *histogram) add(value float64) {
+	if h.weights == nil {
+		h.weights = make([]float64, histogramBuckets)
+	}
+	i := 0
+	if value > histogramFirstBucket {
+		i = min(int(math.Log(value/histogramFirstBucket)/math.Log(histogramRatio)), histogramBuckets-1)
+	}
+	h.weights[i]++
+	h.total++
+}
+
+// decay multiplies the weights of the samples by the factor.
+func (h *histogram) decay(factor float64) {
+	for i := range h.weights {
+		h.weights[i] *= factor
+	}
+	h.total *= factor
+}
+
+// percentile returns the end of the bucket where the cumulative weight
+// reaches the fraction p of the total, 0 if there are no samples.
+func (h *histogram) percentile(p float64) float64 {
+	if h.total <= 0 {
+		return 0
+	}
+	var cumulative float64
+	for i, w := range h.weights {
+		cumulative += w
+		if cumulative >= p*h.total {
+			return bucketStart(i + 1)
+		}
+	}
+	return bucketStart(histogramBuckets)
+}
+
+// recommendation holds the CPU (mCores) and memory (MiB) requests.
+type recommendation struct {
+	cpu    float64
+	memory float64
+}
+
+// verticalPodAutoscaler recommends requests from the CPU and memory usage
+// history of the pods of a deployment and, depending on its mode, applies
+// them to new pods (the admission controller) and to running pods (the
+// updater). The target is the 90th usage percentile, the bounds the 50th
+// and 95th, all with a safety margin. The updater acts on pods whose
+// requests fall outside the bounds.
+//
+// See: https://github.com/kubernetes/autoscaler/tree/master/vertical-pod-autoscaler
+type verticalPodAutoscaler struct {
+	mode       vpaMode
+	resources  vpaResources
+	cpu        histogram
+	memory     histogram
+	lastSample time.Time
+	lastUpdate time.Time
+	target     recommendation
+	lower      recommendation
+	upper      recommendation
+	evictions  int // pods evicted by the updater
+	resizes    int // pods resized in place by the updater
+}
+
+// observe adds the usage of the running containers to the history,
+// decaying the older samples, and updates the recommendation.
+func (v *verticalPodAutoscaler) observe(pods []pod, now time.Time) {
+	if !v.lastSample.IsZero() {
+		factor := math.Pow(0.5, float64(now.Sub(v.lastSample))/float64(vpaHalfLife))
+		v.cpu.decay(factor)
+		v.memory.decay(factor)
+	}
+	v.lastSample = now
+
+	for _, p := range pods {
+		if p.status == podStatusReady || p.status == podStatusNotReady {
+			v.cpu.add(p.cpuUsage)
+			v.memory.add(p.memory)
+		}
+	}
+
+	margin := 1 + vpaSafetyMargin
+	estimate := func(p float64) recommendation {
+		return recommendation{
+			cpu:    max(v.cpu.percentile(p)*margin, vpaMinCPU),
+			memory: max(v.memory.percentile(p)*margin, vpaMinMemory),
+		}
+	}
+	if v.cpu.total > 0 {
+		v.target, v.lower, v.upper = estimate(0.9), estimate(0.5), estimate(0.95)
+	}
+}
+
+// applies tells whether the VPA sets the requests of the pods.
+func (v *verticalPodAutoscaler) applies() bool {
+	switch v.mode {
+	case vpaInitial, vpaRecreate, vpaInPlace:
+		return true
+	}
+	return false
+}
+
+// controlsCPU tells whether the VPA sets the CPU requests of the pods.
+func (v *verticalPodAutoscaler) controlsCPU() bool {
+	return v.applies() && v.resources != vpaMemoryOnly && v.target.cpu > 0
+}
+
+// controlsMemory tells whether the VPA sets the memory requests of the pods.
+func (v *verticalPodAutoscaler) controlsMemory() bool {
+	return v.applies() && v.target.memory > 0
+}
+
+// podRequests returns the CPU request, memory request and CPU limit of a
+// new pod: the pod template, unless the VPA sets the requests, in which
+// case the CPU limit keeps its proportion to the request. The memory
+// request stays under the memory limit.
+func (d *deployment) podRequests() (cpuRequest, memoryRequest, cpuLimit float64) {
+	cpuRequest, memoryRequest, cpuLimit = d.cpuRequest, d.memoryRequest, d.cpuLimit
+	if d.vpa.controlsCPU() {
+		if cpuRequest > 0 {
+			cpuLimit *= d.vpa.target.cpu / cpuRequest
+		}
+		cpuRequest = d.vpa.target.cpu
+	}
+	if d.vpa.controlsMemory() {
+		memoryRequest = d.vpa.target.memory
+		if d.memoryLimit > 0 {
+			memoryRequest = min(memoryRequest, d.memoryLimit)
+		}
+	}
+	return cpuRequest, memoryRequest, cpuLimit
+}
+
+// offRecommendation tells whether the pod requests fall outside the
+// recommended bounds of the resources controlled by the VPA.
+func (d *deployment) offRecommendation(p pod) bool {
+	v := &d.vpa
+	if v.controlsCPU() && (p.cpuRequest < v.lower.cpu || p.cpuRequest > v.upper.cpu) {
+		return true
+	}
+	if v.controlsMemory() && (p.memoryRequest < v.lower.memory || p.memoryRequest > v.upper.memory) {
+		return true
+	}
+	return false
+}
+
+// runVPA observes the pods and, every vpaUpdateInterval in the Recreate
+// and InPlaceOrRecreate modes, updates the ready pods off the recommendation,
+// the farthest first, up to vpaEvictTolerance of the replicas. Like the
+// updater --min-replicas flag, no pod is updated while fewer than
+// vpaMinReplicas pods run, so the only pod is never evicted. Evictions
+// go through the Eviction API, hence they honor the disruption budget.
+// A pod resized in place keeps running, the kubelet applying the new
+// requests after the resize delay, unless its node has no room for them,
+// in which case it is evicted.
+func (d *deployment) runVPA(now time.Time) {
+	v := &d.vpa
+	v.observe(d.podList, now)
+
+	if v.mode != vpaRecreate && v.mode != vpaInPlace {
+		return
+	}
+	if now.Sub(v.lastUpdate) < vpaUpdateInterval {
+		return
+	}
+	v.lastUpdate = now
+
+	if d.countRunning() < vpaMinReplicas {
+		return
+	}
+
+	var candidates []*pod
+	for i := range d.podList {
+		if p := &d.podList[i]; p.status == podStatusReady && !p.resize.pending() && d.offRecommendation(*p) {
+			candidates = append(candidates, p)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b *pod) int {
+		return cmp.Compare(v.distance(*b), v.distance(*a))
+	})
+
+	budget := max(int(vpaEvictTolerance*float64(d.desiredReplicas)), 1)
+	for _, p := range candidates[:min(budget, len(candidates))] {
+		if v.mode == vpaInPlace && d.resizeInPlace(p, now) {
+			v.resizes++
+			d.cluster.events.emit(now, "Normal", "ResizedByVPA", "pod/"+d.podName(*p),
+				fmt.Sprintf("Pod resize to cpu=%dm memory=%dMi requested by VPA Updater",
+					int(p.resize.cpuRequest), int(p.resize.memoryRequest)))
+			continue
+		}
+		if d.cluster.evict(p, d, now) {
+			v.evictions++
+			d.cluster.events.emit(now, "Normal", "EvictedByVPA", "pod/"+d.podName(*p),
+				"Pod was evicted by VPA Updater to apply resource recommendation.")
+		}
+	}
+}
+
+// countRunning returns the number of pods running, not on their way out.
+func (d *deployment) countRunning() int {
+	var count int
+	for _, p := range d.podList {
+		if p.isRunning() && p.status != podStatusTerminating && p.status != podStatusFailed {
+			count++
+		}
+	}
+	return count
+}
+
+// distance returns how far the pod requests are from the target,
+// relative to the target.
+func (v *verticalPodAutoscaler) distance(p pod) float64 {
+	var dist float64
+	if v.target.cpu > 0 {
+		dist = math.Abs(p.cpuRequest-v.target.cpu) / v.target.cpu
+	}
+	if v.target.memory > 0 {
+		dist = max(dist, math.Abs(p.memoryRequest-v.target.memory)/v.target.memory)
+	}
+	return dist
+}
+
+// resizeInPlace requests the resize of the running pod to the
+// recommendation, if its node has room for it. It returns false otherwise.
+func (d *deployment) resizeInPlace(p *pod, now time.Time) bool {
+	cpuRequest, memoryRequest, cpuLimit := d.podRequests()
+	n := d.cluster.findNode(p.node)
+	if n == nil || !n.fits(cpuRequest-p.cpuRequest, memoryRequest-p.memoryRequest) {
+		return false
+	}
+	p.requestResize(cpuRequest, memoryRequest, cpuLimit, now)
+	return true
+}
+
+// updateVPALegend shows the VPA recommendation, its updates and whether it
+// fights the CPU HPA, which it does for any target away from the utilization
+// of 1/(1+vpaSafetyMargin) it holds the pods at.
+func updateVPALegend(legend js.Value, d *deployment, targetCPUUtilization int) {
+	v := &d.vpa
+	text := "no usage samples yet"
+	if v.target.cpu > 0 {
+		text = fmt.Sprintf("cpu %dm (%dm-%dm), memory %dMi (%dMi-%dMi)",
+			int(v.target.cpu), int(v.lower.cpu), int(v.upper.cpu),
+			int(v.target.memory), int(v.lower.memory), int(v.upper.memory))
+	}
+	legend.Call("querySelector", ".vpa-target").Set("innerText", text)
+	legend.Call("querySelector", ".vpa-updates").Set("innerText",
+		fmt.Sprintf("%d evicted, %d resized in place", v.evictions, v.resizes))
+
+	equilibrium := 100 / (1 + vpaSafetyMargin)
+	var verdict string
+	switch {
+	case !v.applies():
+		verdict = "safe: VPA recommends only"
+	case v.resources == vpaMemoryOnly:
+		verdict = "safe: VPA controls memory, HPA scales on CPU"
+	case float64(targetCPUUtilization) < equilibrium*(1-scaleTolerance):
+		verdict = fmt.Sprintf("CONFLICT: VPA holds CPU near %.0f%% of request, above the %d%% HPA target: runaway scale up",
+			equilibrium, targetCPUUtilization)
+	case float64(targetCPUUtilization) > equilibrium*(1+scaleTolerance):
+		verdict = fmt.Sprintf("CONFLICT: VPA holds CPU near %.0f%% of request, below the %d%% HPA target: scale down to min",
+			equilibrium, targetCPUUtilization)
+	default:
+		verdict = fmt.Sprintf("CONFLICT: VPA and HPA both act on CPU requests (equilibrium near %.0f%%)", equilibrium)
+	}
+	legend.Call("querySelector", ".vpa-hpa-conflict").Set("innerText", verdict)
+}
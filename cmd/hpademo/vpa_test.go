@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+// withinBucket tells whether the percentile reported for samples of value
+// is the end of the bucket holding value: above it by at most one bucket.
+func withinBucket(percentile, value float64) bool {
+	return percentile > value && percentile <= value*histogramRatio
+}
+
+func TestHistogramPercentileAfterDecay(t *testing.T) {
+	var h histogram
+	if got := h.percentile(0.9); got != 0 {
+		t.Fatalf("empty histogram: percentile %v, want 0", got)
+	}
+
+	for range 10 {
+		h.add(100)
+	}
+	if got := h.percentile(0.5); !withinBucket(got, 100) {
+		t.Fatalf("median %v, want the bucket of 100", got)
+	}
+
+	// the old samples fade away: a few recent ones outweigh them
+	h.decay(0.01)
+	for range 2 {
+		h.add(1000)
+	}
+	if got := h.percentile(0.5); !withinBucket(got, 1000) {
+		t.Errorf("median after decay %v, want the bucket of 1000", got)
+	}
+	// yet the old samples still hold the bottom 4% of the weight
+	if got := h.percentile(0.04); !withinBucket(got, 100) {
+		t.Errorf("4th percentile after decay %v, want the bucket of 100", got)
+	}
+	if h.total != 10*0.01+2 {
+		t.Errorf("total weight %v, want %v", h.total, 10*0.01+2)
+	}
+}
+
+func TestHistogramOutOfRange(t *testing.T) {
+	var h histogram
+	h.add(0.2) // below the first bucket
+	if got := h.percentile(1); got != bucketStart(1) {
+		t.Errorf("tiny sample: percentile %v, want the end of the first bucket %v", got, bucketStart(1))
+	}
+
+	h = histogram{}
+	h.add(1e9) // beyond the last bucket
+	if got := h.percentile(1); got != bucketStart(histogramBuckets) {
+		t.Errorf("huge sample: percentile %v, want the end of the last bucket %v", got, bucketStart(histogramBuckets))
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"syscall/js"
+	"time"
+)
+
+// maxWorkloads is the number of workloads that can share the cluster.
+const maxWorkloads = 3
+
+// workloadColors holds the colors of the workloads in the combined chart,
+// listed from the bottom of the stack up.
+var workloadColors = [maxWorkloads]string{
+	"rgba(0, 0, 255, 0.4)",
+	"rgba(0, 160, 0, 0.5)",
+	"rgba(255, 140, 0, 0.6)",
+}
+
+// workloadSettings holds the values of the workload controls, by element id.
+// The controls show the settings of the selected workload only,
+// hence every workload keeps its own copy.
+type workloadSettings map[string]string
+
+func (s workloadSettings) int(ctrl sliderControl) int {
+	i, err := strconv.Atoi(s[ctrl.id])
+	if err != nil {
+		fmt.Printf("Error converting %s setting to int: %v\n", ctrl.id, err)
+		return 0
+	}
+	return i
+}
+
+func (s workloadSettings) value(el js.Value) string {
+	return s[el.Get("id").String()]
+}
+
+// workload is a deployment with its own load, settings and HPA,
+// competing with the other workloads for the cluster nodes.
+type workload struct {
+	name              string
+	active            bool // inactive workloads have no load and no pods
+	deploy            *deployment
+	settings          workloadSettings
+	shedder           loadShedder
+	balance           *balancer
+	sli               availability
+	chart             chart
+	demand            float64 // traffic offered this second, in mCores at the baseline request cost
+	errorRate         float64 // latest per-second error rate
+	lastHPAEvaluation int
+	lastScaleDown     time.Time
+}
+
+func newWorkload(document js.Value, name string, c *cluster, rng *rand.Rand, historySize int) *workload {
+	return &workload{
+		name: name,
+		deploy: &deployment{
+			name:            name,
+			desiredReplicas: 1,
+			rng:             rng,
+			cluster:         c,
+		},
+		settings: workloadSettings{},
+		balance:  newBalancer(rng),
+		sli:      newAvailability(historySize),
+		chart:    newChart(document, historySize),
+	}
+}
+
+// save copies the values of the workload controls into the workload settings.
+func (w *workload) save(controls podControls) {
+	for _, ctrl := range controls.workloadSliders() {
+		w.settings[ctrl.id] = ctrl.slider.Get("value").String()
+	}
+	for _, el := range controls.workloadSelects() {
+		w.settings[el.Get("id").String()] = el.Get("value").String()
+	}
+}
+
+// load shows the workload settings in the workload controls.
+func (w *workload) load(controls podControls) {
+	for _, ctrl := range controls.workloadSliders() {
+		value := w.settings[ctrl.id]
+		ctrl.slider.Set("value", value)
+		ctrl.textBox.Set("value", value)
+	}
+	for _, el := range controls.workloadSelects() {
+		el.Set("value", w.settings.value(el))
+	}
+}
+
+// autoscale evaluates the workload HPA every 15 seconds and returns the
+// desired number of pods. A scaling decision is saved into the number
+// of pods setting, and shown in its control if the workload is selected.
+func (w *workload) autoscale(controls podControls, selected bool) int {
+	s := w.settings
+	oldPodValue := s.int(controls.sliderNumberOfPods)
+
+	w.lastHPAEvaluation++
+	if w.lastHPAEvaluation < 15 {
+		return oldPodValue
+	}
+	w.lastHPAEvaluation = 0
+
+	newPodValue, isScaleToleranceAllowed := runHPADemoSimulation(hpaSpec{
+		currentPods:          oldPodValue,
+		targetCPUUtilization: s.int(controls.sliderHPATargetCPUUtilization),
+		minReplicas:          s.int(controls.sliderHPAMinReplicas),
+		maxReplicas:          s.int(controls.sliderHPAMaxReplicas),
+	}, w.deploy.metrics())
+
+	if !isScaleToleranceAllowed || newPodValue == oldPodValue {
+		return oldPodValue // within tolerance or pods unchanged
+	}
+
+	if newPodValue < oldPodValue {
+		// scaling down, check stabilization window
+		elapSecs := time.Since(w.lastScaleDown).Seconds()
+		scaleDownWindow := float64(s.int(controls.sliderScaleDownStabilizationWindow))
+		if elapSecs <= scaleDownWindow {
+			fmt.Printf("%s: lastScaleDown=%v <= scaleDownStabilizationWindow=%v, not scaling down\n",
+				w.name, elapSecs, scaleDownWindow)
+			return oldPodValue
+		}
+		w.lastScaleDown = time.Now()
+	}
+
+	// update number of pods to reflect HPA decision
+	s[controls.sliderNumberOfPods.id] = strconv.Itoa(newPodValue)
+	if selected {
+		controls.sliderNumberOfPods.slider.Set("value", newPodValue)
+		controls.sliderNumberOfPods.textBox.Set("value", newPodValue)
+	}
+
+	return newPodValue
+}
+
+// configure applies the workload settings to its deployment,
+// and sets the traffic offered to the workload this second.
+func (w *workload) configure(controls podControls) {
+	s := w.settings
+	d := w.deploy
+	w.demand = 0
+	if w.active {
+		w.demand = float64(s.int(controls.sliderCPUUsage))
+	}
+	d.schedulingTime = time.Second * time.Duration(s.int(controls.sliderPODSchedulingTime))
+	d.imagePullTime = time.Second * time.Duration(s.int(controls.sliderPODImagePullTime))
+	d.startupTime = time.Second * time.Duration(s.int(controls.sliderPODStartupTime))
+	d.startupDist = startupDistribution(s.value(controls.selectStartupDistribution))
+	d.startupSpread = float64(s.int(controls.sliderStartupSpread)) / 100
+	d.preStopTime = time.Second * time.Duration(s.int(controls.sliderPODPreStopTime))
+	d.stopTime = time.Second * time.Duration(s.int(controls.sliderPODStopTime))
+	d.gracePeriod = time.Second * time.Duration(s.int(controls.sliderPODGracePeriod))
+	d.slowStart = time.Second * time.Duration(s.int(controls.sliderSlowStartWindow))
+	d.coldPenalty = float64(s.int(controls.sliderColdCPUPenalty)) / 100
+	d.warmUpTime = time.Second * time.Duration(s.int(controls.sliderColdWarmUpTime))
+	d.startupCPU = float64(s.int(controls.sliderPODStartupCPU))
+	d.readinessFail = time.Second * time.Duration(s.int(controls.sliderReadinessFailure))
+	d.livenessFail = time.Second * time.Duration(s.int(controls.sliderLivenessFailure))
+	d.startupFailure = float64(s.int(controls.sliderStartupFailure)) / 100
+	d.crashRate = float64(s.int(controls.sliderCrashRate))
+	d.memoryBaseline = float64(s.int(controls.sliderPODMemoryBaseline))
+	d.memoryPerLoad = float64(s.int(controls.sliderPODMemoryPerLoad)) / 100
+	d.memoryLeakRate = float64(s.int(controls.sliderPODMemoryLeakRate))
+	d.memoryLimit = float64(s.int(controls.sliderPODMemoryLimit))
+	d.cpuRequest = float64(s.int(controls.sliderPODCPURequest))
+	d.memoryRequest = float64(s.int(controls.sliderPODMemoryRequest))
+	d.cpuLimit = float64(s.int(controls.sliderPODCPULimit))
+	d.applyQoS(qosPreset(s.value(controls.selectQoSClass)))
+	d.resizeDelay = time.Second * time.Duration(s.int(controls.sliderResizeDelay))
+	d.burstiness = float64(s.int(controls.sliderLoadBurstiness)) / 100
+	d.quota = resourceQuota{
+		pods:        s.int(controls.sliderQuotaPods),
+		requestsCPU: float64(s.int(controls.sliderQuotaRequestsCPU)),
+		limitsCPU:   float64(s.int(controls.sliderQuotaLimitsCPU)),
+	}
+	d.maxSurge = float64(s.int(controls.sliderMaxSurge)) / 100
+	d.maxUnavailable = float64(s.int(controls.sliderMaxUnavailable)) / 100
+	d.priority = s.int(controls.sliderWorkloadPriority)
+	d.preemptLower = s.value(controls.selectPreemptionPolicy) == "PreemptLowerPriority"
+	d.kind = workloadKind(s.value(controls.selectWorkloadKind))
+	d.podManagement = podManagementPolicy(s.value(controls.selectPodManagementPolicy))
+	d.pdb = podDisruptionBudget{
+		policy:  pdbPolicy(s.value(controls.selectPDBPolicy)),
+		percent: float64(s.int(controls.sliderPDBValue)) / 100,
+	}
+	d.vpa.mode = vpaMode(s.value(controls.selectVPAMode))
+	d.vpa.resources = vpaResources(s.value(controls.selectVPAResources))
+	d.spread = topologySpread{
+		whenUnsatisfiable: spreadPolicy(s.value(controls.selectSpreadPolicy)),
+		maxSkew:           s.int(controls.sliderMaxSkew),
+	}
+}
+
+// serve offers the workload load to its pods, records the outcome into
+// the workload SLI and pushes it into the workload charts, along with
+// the cluster-wide values already in the sample.
+func (w *workload) serve(controls podControls, sample chartSample) {
+	s := w.settings
+	d := w.deploy
+
+	//
+	// evaluate per pod load
+	//
+
+	totalCPUUsage := w.demand * float64(s.int(controls.sliderCPUCost)) / 100
+
+	w.shedder.policy = sheddingPolicy(s.value(controls.selectSheddingPolicy))
+	w.shedder.queueSize = float64(s.int(controls.sliderSheddingQueueSize))
+	w.shedder.timeout = s.int(controls.sliderSheddingQueueTimeout)
+
+	w.balance.strategy = balancingStrategy(s.value(controls.selectBalancingStrategy))
+	w.balance.skew = float64(s.int(controls.sliderStickySkew)) / 100
+
+	offeredLoad := w.shedder.offer(totalCPUUsage)
+
+	metLoad := d.serveLoad(w.balance, offeredLoad)
+	podLoadMin, podLoadAvg, podLoadMax := d.cpuUsageStats()
+
+	//
+	// evaluate per pod memory
+	//
+
+	d.updateMemory(time.Now())
+	podMemoryMin, podMemoryAvg, podMemoryMax := d.memoryStats()
+
+	//
+	// evaluate load shedding
+	//
+
+	rejectedLoad, unmetLoad := w.shedder.shed(totalCPUUsage, metLoad)
+	w.errorRate = errorRate(totalCPUUsage, rejectedLoad)
+	w.sli.record(totalCPUUsage, rejectedLoad)
+
+	sample.replicas = d.getReplicas()
+	sample.desired = d.desiredReplicas
+	sample.byStatus = d.countByStatus()
+	sample.unschedulable = d.countUnschedulable()
+	sample.podLoad = int(podLoadAvg)
+	sample.podLoadMin = int(podLoadMin)
+	sample.podLoadMax = int(podLoadMax)
+	sample.podMemory = int(podMemoryAvg)
+	sample.podMemoryMin = int(podMemoryMin)
+	sample.podMemoryMax = int(podMemoryMax)
+	sample.restarts = d.restarts
+	sample.unmetLoad = int(unmetLoad)
+	sample.errorRate = int(math.Round(w.errorRate))
+	sample.podsByZone, _ = d.zoneStats()
+	throttled, usageOfRequest, _ := d.throttleStats()
+	sample.throttled = int(math.Round(throttled))
+	sample.usageOfRequest = int(math.Round(usageOfRequest))
+
+	updateChart(&w.chart, sample)
+}
+
+// byPriority returns the workloads sorted from the highest priority down,
+// the order in which the scheduler queue serves their pending pods.
+func byPriority(workloads []*workload) []*workload {
+	sorted := slices.Clone(workloads)
+	slices.SortStableFunc(sorted, func(a, b *workload) int {
+		return cmp.Compare(b.deploy.priority, a.deploy.priority)
+	})
+	return sorted
+}
+
+// updateWorkloadsLegend shows the ready pods, load and error rate of every workload.
+func updateWorkloadsLegend(legend js.Value, workloads []*workload) {
+	for _, w := range workloads {
+		text := "inactive"
+		if w.active {
+			text = fmt.Sprintf("%d/%d ready, %dm load, %.1f%% errors",
+				w.deploy.countStatus(podStatusReady), w.deploy.desiredReplicas, int(w.demand), w.errorRate)
+		}
+		legend.Call("querySelector", "."+w.name).Set("innerText", text)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"syscall/js"
+	"time"
+)
+
+// maxZones is the number of availability zones nodes can be spread across.
+const maxZones = 3
+
+// zoneColors holds the colors of the zones in the pods per zone chart,
+// listed from the bottom of the stack up.
+var zoneColors = [maxZones]string{
+	"rgba(0, 0, 255, 0.4)",
+	"rgba(0, 160, 0, 0.5)",
+	"rgba(255, 140, 0, 0.6)",
+}
+
+// zoneName returns the zone name, e.g. zone-a.
+func zoneName(zone int) string {
+	return fmt.Sprintf("zone-%c", 'a'+zone)
+}
+
+// spreadPolicy is the whenUnsatisfiable of a topology spread constraint.
+type spreadPolicy string
+
+const (
+	spreadNone           spreadPolicy = "none"           // no constraint, pods go to the first node that fits
+	spreadDoNotSchedule  spreadPolicy = "DoNotSchedule"  // pods exceeding maxSkew stay pending
+	spreadScheduleAnyway spreadPolicy = "ScheduleAnyway" // zones with fewer pods are preferred
+)
+
+// topologySpread is a topologySpreadConstraint of a deployment on the
+// topology.kubernetes.io/zone key. The skew of a zone is how many more
+// pods of the deployment it holds than the zone holding the fewest.
+//
+// See: https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+type topologySpread struct {
+	whenUnsatisfiable spreadPolicy
+	maxSkew           int
+}
+
+// setZones sets the number of zones, moving the nodes of the zones
+// removed to the remaining ones.
+func (c *cluster) setZones(zones int) {
+	c.zones = max(zones, 1)
+	for i := range c.nodes {
+		c.nodes[i].zone %= c.zones
+	}
+}
+
+// pickZone returns the zone of a new node: the zone with the fewest nodes
+// not down, like a node group balancing its instances across zones.
+func (c *cluster) pickZone() int {
+	var count [maxZones]int
+	for _, n := range c.nodes {
+		count[n.zone]++
+	}
+	best := -1
+	for z := range max(c.zones, 1) {
+		if c.zoneDown[z] {
+			continue
+		}
+		if best < 0 || count[z] < count[best] {
+			best = z
+		}
+	}
+	return max(best, 0)
+}
+
+// zoneOutage takes the zone down for the duration: its nodes become
+// unreachable and every pod in the zone is lost.
+func (c *cluster) zoneOutage(zone int, duration time.Duration, now time.Time) {
+	c.outageUntil[zone] = now.Add(duration)
+	c.runOutages(now)
+}
+
+// runOutages takes zones down and back up as their outages start and end.
+// Nodes of a zone down are NotReady, hence no pod is scheduled on them,
+// but they are still domains of the topology spread constraints.
+func (c *cluster) runOutages(now time.Time) {
+	for z := range maxZones {
+		down := now.Before(c.outageUntil[z])
+		if down == c.zoneDown[z] {
+			continue
+		}
+		c.zoneDown[z] = down
+
+		var nodes int
+		for i := range c.nodes {
+			if n := &c.nodes[i]; n.zone == z {
+				n.unreachable = down
+				nodes++
+			}
+		}
+
+		if !down {
+			c.events.emit(now, "Normal", "ZoneRecovered", "zone/"+zoneName(z),
+				fmt.Sprintf("Zone reachable again, %d nodes Ready", nodes))
+			continue
+		}
+
+		var lost int
+		for _, p := range c.pods() {
+			if !p.holdsResources() {
+				continue
+			}
+			if n := c.findNode(p.node); n != nil && n.zone == z {
+				p.setStatus(podStatusFailed, now)
+				lost++
+			}
+		}
+		c.events.emit(now, "Warning", "ZoneOutage", "zone/"+zoneName(z),
+			fmt.Sprintf("Zone unreachable, %d nodes NotReady, %d pods lost", nodes, lost))
+	}
+}
+
+// countZonePods counts the pods of the deployment in every zone, as the
+// topology spread constraint does: terminating and failed pods are left out.
+func (d *deployment) countZonePods() {
+	d.zonePods = [maxZones]int{}
+	for _, p := range d.podList {
+		if !p.holdsResources() || p.status == podStatusTerminating {
+			continue
+		}
+		if n := d.cluster.findNode(p.node); n != nil {
+			d.zonePods[n.zone]++
+		}
+	}
+}
+
+// spreadZones returns the zones a new pod of the deployment may be bound to,
+// in the order the scheduler prefers them, or nil if the deployment has no
+// topology spread constraint. Every zone with nodes is a domain, whether
+// its nodes are ready or not. With DoNotSchedule, zones where the pod would
+// exceed maxSkew are left out.
+func (d *deployment) spreadZones() []int {
+	policy := d.spread.whenUnsatisfiable
+	if policy != spreadDoNotSchedule && policy != spreadScheduleAnyway {
+		return nil
+	}
+
+	var domains []int
+	for z := range maxZones {
+		if slices.ContainsFunc(d.cluster.nodes, func(n node) bool { return n.zone == z }) {
+			domains = append(domains, z)
+		}
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+
+	minPods := math.MaxInt
+	for _, z := range domains {
+		minPods = min(minPods, d.zonePods[z])
+	}
+
+	var zones []int
+	for _, z := range domains {
+		if policy == spreadDoNotSchedule && d.zonePods[z]+1-minPods > d.spread.maxSkew {
+			continue
+		}
+		zones = append(zones, z)
+	}
+	slices.SortStableFunc(zones, func(a, b int) int {
+		return cmp.Compare(d.zonePods[a], d.zonePods[b])
+	})
+	return zones
+}
+
+// allDown tells whether every one of the zones is down.
+func (c *cluster) allDown(zones []int) bool {
+	for _, z := range zones {
+		if !c.zoneDown[z] {
+			return false
+		}
+	}
+	return true
+}
+
+// zoneLossSurvivors returns how many of minReplicas pods are left after
+// losing the zone holding the most of them. Without a DoNotSchedule
+// constraint nothing keeps every pod from landing in the same zone.
+func (d *deployment) zoneLossSurvivors(minReplicas int) int {
+	var zones int
+	for z := range maxZones {
+		if slices.ContainsFunc(d.cluster.nodes, func(n node) bool { return n.zone == z }) {
+			zones++
+		}
+	}
+	if zones < 2 {
+		return 0
+	}
+	worst := minReplicas
+	if d.spread.whenUnsatisfiable == spreadDoNotSchedule {
+		// the other zones hold at least worst-maxSkew pods each
+		worst = min((minReplicas+(zones-1)*d.spread.maxSkew)/zones, minReplicas)
+	}
+	return minReplicas - worst
+}
+
+// zoneStats returns the ready pods of the deployment and the load
+// they serve in every zone.
+func (d *deployment) zoneStats() (ready [maxZones]int, load [maxZones]float64) {
+	for _, p := range d.podList {
+		if p.status != podStatusReady {
+			continue
+		}
+		if n := d.cluster.findNode(p.node); n != nil {
+			ready[n.zone]++
+			load[n.zone] += p.load
+		}
+	}
+	return ready, load
+}
+
+// updateZoneLegend shows the nodes, ready pods and load per zone, and whether
+// the minReplicas pods left after losing a zone serve the load at their limit.
+func updateZoneLegend(legend js.Value, d *deployment, minReplicas int, offered float64) {
+	ready, load := d.zoneStats()
+	var nodes [maxZones]int
+	for _, n := range d.cluster.nodes {
+		nodes[n.zone]++
+	}
+	for z := range maxZones {
+		text := fmt.Sprintf("%d nodes, %d ready pods, %dm load", nodes[z], ready[z], int(load[z]))
+		switch {
+		case d.cluster.zoneDown[z]:
+			text = "DOWN: " + text
+		case z >= d.cluster.zones && nodes[z] == 0:
+			text = "not in use"
+		}
+		legend.Call("querySelector", "."+zoneName(z)).Set("innerText", text)
+	}
+
+	survivors := d.zoneLossSurvivors(minReplicas)
+	perPod := d.cpuLimit
+	if perPod <= 0 {
+		perPod = d.cpuRequest
+	}
+	capacity := float64(survivors) * perPod
+	verdict := "OK"
+	if capacity < offered {
+		verdict = "AT RISK"
+	}
+	legend.Call("querySelector", ".zone-survivability").Set("innerText",
+		fmt.Sprintf("%s: %d of %d min replicas survive, %dm capacity for %dm load",
+			verdict, survivors, minReplicas, int(capacity), int(offered)))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// zonedDeployment returns a deployment on a cluster with one node in each
+// of the zones, holding zonePods pods of the deployment per zone.
+func zonedDeployment(policy spreadPolicy, maxSkew int, zonePods ...int) *deployment {
+	c := &cluster{zones: len(zonePods)}
+	for z := range zonePods {
+		c.nodes = append(c.nodes, node{id: z + 1, zone: z})
+	}
+	d := &deployment{cluster: c, spread: topologySpread{whenUnsatisfiable: policy, maxSkew: maxSkew}}
+	copy(d.zonePods[:], zonePods)
+	return d
+}
+
+func TestSpreadZonesMaxSkew(t *testing.T) {
+	// zone-a already holds one pod more than the others
+	strict := zonedDeployment(spreadDoNotSchedule, 1, 2, 1, 1)
+	if got := strict.spreadZones(); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("DoNotSchedule zones %v, want zone-a left out: [1 2]", got)
+	}
+
+	// ScheduleAnyway only prefers the zones with fewer pods
+	soft := zonedDeployment(spreadScheduleAnyway, 1, 2, 1, 1)
+	if got := soft.spreadZones(); !slices.Equal(got, []int{1, 2, 0}) {
+		t.Errorf("ScheduleAnyway zones %v, want every zone, fewest pods first: [1 2 0]", got)
+	}
+
+	// a larger skew lets zone-a take another pod
+	loose := zonedDeployment(spreadDoNotSchedule, 2, 2, 1, 1)
+	if got := loose.spreadZones(); len(got) != 3 {
+		t.Errorf("DoNotSchedule maxSkew 2 zones %v, want all 3", got)
+	}
+
+	if got := zonedDeployment(spreadNone, 1, 2, 1, 1).spreadZones(); got != nil {
+		t.Errorf("no constraint, zones %v, want nil", got)
+	}
+}
+
+func TestSpreadZonesAllDown(t *testing.T) {
+	d := zonedDeployment(spreadDoNotSchedule, 1, 2, 1, 1)
+	zones := d.spreadZones()
+
+	d.cluster.zoneDown[1] = true
+	if d.cluster.allDown(zones) {
+		t.Fatal("zone-c is up, yet the zones are reported down")
+	}
+
+	// zone-a is up, but the constraint keeps the pod out of it
+	d.cluster.zoneDown[2] = true
+	if !d.cluster.allDown(zones) {
+		t.Error("every zone the constraint allows is down, the pod should be zone blocked")
+	}
+}
+
+func TestZoneLossSurvivors(t *testing.T) {
+	// survivors = n - min(floor((n+(z-1)s)/z), n) for DoNotSchedule
+	for _, c := range []struct{ zones, minReplicas, maxSkew, want int }{
+		{zones: 1, minReplicas: 4, maxSkew: 1, want: 0}, // no other zone to survive in
+		{zones: 2, minReplicas: 4, maxSkew: 1, want: 2}, // 2+2, or 3+1 is over the skew
+		{zones: 2, minReplicas: 5, maxSkew: 1, want: 2}, // 3+2
+		{zones: 3, minReplicas: 6, maxSkew: 1, want: 4}, // 2+2+2
+		{zones: 3, minReplicas: 3, maxSkew: 2, want: 1}, // 2+1+0
+		{zones: 3, minReplicas: 2, maxSkew: 5, want: 0}, // both pods may share a zone
+	} {
+		pods := make([]int, c.zones)
+		d := zonedDeployment(spreadDoNotSchedule, c.maxSkew, pods...)
+		if got := d.zoneLossSurvivors(c.minReplicas); got != c.want {
+			t.Errorf("%d zones, minReplicas %d, maxSkew %d: %d survivors, want %d",
+				c.zones, c.minReplicas, c.maxSkew, got, c.want)
+		}
+	}
+
+	// without DoNotSchedule every pod may land in the lost zone
+	if got := zonedDeployment(spreadScheduleAnyway, 1, 0, 0, 0).zoneLossSurvivors(6); got != 0 {
+		t.Errorf("ScheduleAnyway: %d survivors, want 0", got)
+	}
+}